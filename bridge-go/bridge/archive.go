@@ -0,0 +1,342 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// ErrArchiveDisabled is returned when exporting without an archive configured
+var ErrArchiveDisabled = errors.New("message archive is not enabled (set archivePath)")
+
+// ArchiveRecord is one line of a thread archive
+type ArchiveRecord struct {
+	Kind         string        `json:"kind"`      // "message", "edit", "reaction" or "unsend"
+	Direction    string        `json:"direction"` // "incoming" or "outgoing"
+	ThreadID     int64         `json:"threadId"`
+	ChatJID      string        `json:"chatJid,omitempty"`
+	MessageID    string        `json:"messageId"`
+	SenderID     int64         `json:"senderId,omitempty"`
+	Text         string        `json:"text,omitempty"`
+	PreviousText *string       `json:"previousText,omitempty"`
+	Reaction     string        `json:"reaction,omitempty"` // Empty on a reaction record means it was removed
+	Attachments  []*Attachment `json:"attachments,omitempty"`
+	ReplyTo      *ReplyTo      `json:"replyTo,omitempty"`
+	IsE2EE       bool          `json:"isE2EE,omitempty"`
+	TimestampMs  int64         `json:"timestampMs"`
+	RecordedAtMs int64         `json:"recordedAtMs"`
+}
+
+// messageArchive appends records to one JSONL file per thread
+type messageArchive struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func newMessageArchive(dir string) (*messageArchive, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &messageArchive{dir: dir}, nil
+}
+
+func (a *messageArchive) threadPath(threadID int64) string {
+	if threadID == 0 {
+		// Events whose thread couldn't be resolved
+		return filepath.Join(a.dir, "unresolved.jsonl")
+	}
+	return filepath.Join(a.dir, strconv.FormatInt(threadID, 10)+".jsonl")
+}
+
+func (a *messageArchive) append(record *ArchiveRecord) error {
+	record.RecordedAtMs = timeNowMs()
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.OpenFile(a.threadPath(record.ThreadID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (a *messageArchive) readThread(threadID int64) ([]*ArchiveRecord, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := os.Open(a.threadPath(threadID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []*ArchiveRecord{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []*ArchiveRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("corrupt archive record: %w", err)
+		}
+		records = append(records, &record)
+	}
+	return records, scanner.Err()
+}
+
+// archiveRecord appends a record if the archive is enabled
+func (c *Client) archiveRecord(record *ArchiveRecord) {
+	if c.archive == nil {
+		return
+	}
+	if record.Direction == "" {
+		record.Direction = "incoming"
+		if record.SenderID != 0 && record.SenderID == c.FBID {
+			record.Direction = "outgoing"
+		}
+	}
+	if err := c.archive.append(record); err != nil {
+		c.Logger.Warn().Err(err).Int64("thread_id", record.ThreadID).Msg("Failed to write archive record")
+	}
+}
+
+// archiveEvent archives message, edit, reaction and unsend events
+func (c *Client) archiveEvent(eventType EventType, data interface{}) {
+	if c.archive == nil {
+		return
+	}
+	switch evt := data.(type) {
+	case *Message:
+		c.archiveRecord(&ArchiveRecord{
+			Kind:        "message",
+			ThreadID:    evt.ThreadID,
+			MessageID:   evt.ID,
+			SenderID:    evt.SenderID,
+			Text:        evt.Text,
+			Attachments: evt.Attachments,
+			ReplyTo:     evt.ReplyTo,
+			TimestampMs: evt.TimestampMs,
+		})
+	case *E2EEMessage:
+		c.archiveRecord(&ArchiveRecord{
+			Kind:        "message",
+			ThreadID:    evt.ThreadID,
+			ChatJID:     evt.ChatJID,
+			MessageID:   evt.ID,
+			SenderID:    evt.SenderID,
+			Text:        evt.Text,
+			Attachments: evt.Attachments,
+			ReplyTo:     evt.ReplyTo,
+			IsE2EE:      true,
+			TimestampMs: evt.TimestampMs,
+		})
+	case *MessageEditEvent:
+		c.archiveRecord(&ArchiveRecord{
+			Kind:         "edit",
+			ThreadID:     evt.ThreadID,
+			ChatJID:      evt.ChatJID,
+			MessageID:    evt.MessageID,
+			SenderID:     evt.SenderID,
+			Text:         evt.NewText,
+			PreviousText: evt.PreviousText,
+			IsE2EE:       evt.IsE2EE,
+			TimestampMs:  evt.TimestampMs,
+		})
+	case *MessageUnsendEvent:
+		c.archiveRecord(&ArchiveRecord{
+			Kind:         "unsend",
+			ThreadID:     evt.ThreadID,
+			ChatJID:      evt.ChatJID,
+			MessageID:    evt.MessageID,
+			SenderID:     evt.SenderID,
+			PreviousText: evt.PreviousText,
+			Attachments:  evt.Attachments,
+			IsE2EE:       evt.IsE2EE,
+			TimestampMs:  evt.TimestampMs,
+		})
+	case *ReactionEvent:
+		c.archiveRecord(&ArchiveRecord{
+			Kind:        "reaction",
+			ThreadID:    evt.ThreadID,
+			MessageID:   evt.MessageID,
+			SenderID:    evt.ActorID,
+			Reaction:    evt.Reaction,
+			TimestampMs: evt.TimestampMs,
+		})
+	case map[string]any:
+		if eventType != EventTypeE2EEReaction {
+			return
+		}
+		record := &ArchiveRecord{Kind: "reaction", IsE2EE: true, TimestampMs: timeNowMs()}
+		record.MessageID, _ = evt["messageId"].(string)
+		record.ChatJID, _ = evt["chatJid"].(string)
+		record.SenderID, _ = evt["senderId"].(int64)
+		record.Reaction, _ = evt["reaction"].(string)
+		if jid, err := waTypes.ParseJID(record.ChatJID); err == nil {
+			record.ThreadID, _ = strconv.ParseInt(jid.User, 10, 64)
+		}
+		c.archiveRecord(record)
+	}
+}
+
+// uploadedAttachment describes media we uploaded for an E2EE send
+func uploadedAttachment(attType string, uploaded whatsmeow.UploadResponse, mimeType string, size int, att *Attachment) *Attachment {
+	att.Type = attType
+	att.MimeType = mimeType
+	att.FileSize = int64(size)
+	att.MediaKey = uploaded.MediaKey
+	att.MediaSHA256 = uploaded.FileSHA256
+	att.MediaEncSHA256 = uploaded.FileEncSHA256
+	att.DirectPath = uploaded.DirectPath
+	return att
+}
+
+// recordSentE2EE adds a sent E2EE message to the state mirror and archive,
+// since our own E2EE messages aren't echoed back to us
+func (c *Client) recordSentE2EE(chatJID waTypes.JID, msgID string, ts time.Time, text string, att *Attachment) {
+	threadID, _ := strconv.ParseInt(chatJID.User, 10, 64)
+	msg := &E2EEMessage{
+		ID:          msgID,
+		ThreadID:    threadID,
+		ChatJID:     chatJID.String(),
+		SenderID:    c.FBID,
+		Text:        text,
+		TimestampMs: ts.UnixMilli(),
+		Attachments: []*Attachment{},
+	}
+	if c.E2EE != nil && c.E2EE.Store.ID != nil {
+		msg.SenderJID = c.E2EE.Store.ID.String()
+	}
+	if att != nil {
+		msg.Attachments = append(msg.Attachments, att)
+	}
+	c.state.putMessage(storedFromE2EEMessage(msg))
+	c.archiveEvent(EventTypeE2EEMessage, msg)
+}
+
+// ExportThreadOptions for exporting an archived thread
+type ExportThreadOptions struct {
+	ThreadID   int64  `json:"threadId"`
+	Format     string `json:"format"`               // "jsonl" (default) or "html"
+	OutputPath string `json:"outputPath,omitempty"` // If empty, the export is returned as data
+}
+
+// ExportThreadResult result of exporting a thread
+type ExportThreadResult struct {
+	Format  string `json:"format"`
+	Records int    `json:"records"`
+	Path    string `json:"path,omitempty"`
+	Data    string `json:"data,omitempty"`
+}
+
+// ExportThread exports the archive of a thread as JSONL or a self-contained HTML transcript
+func (c *Client) ExportThread(opts *ExportThreadOptions) (*ExportThreadResult, error) {
+	if c.archive == nil {
+		return nil, ErrArchiveDisabled
+	}
+	format := opts.Format
+	if format == "" {
+		format = "jsonl"
+	}
+	records, err := c.archive.readThread(opts.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(&buf)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return nil, err
+			}
+		}
+	case "html":
+		if err := c.renderArchiveHTML(&buf, opts.ThreadID, records); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	result := &ExportThreadResult{Format: format, Records: len(records)}
+	if opts.OutputPath == "" {
+		result.Data = buf.String()
+		return result, nil
+	}
+	if err := os.WriteFile(opts.OutputPath, buf.Bytes(), 0600); err != nil {
+		return nil, err
+	}
+	result.Path = opts.OutputPath
+	return result, nil
+}
+
+var archiveHTMLTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(ms int64) string {
+		return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; max-width: 860px; margin: 2em auto; color: #1c1e21; }
+h1 { font-size: 1.4em; }
+.record { border-left: 3px solid #ccd0d5; margin: .6em 0; padding: .3em .8em; }
+.outgoing { border-color: #0084ff; }
+.meta { color: #65676b; font-size: .85em; }
+.edit, .unsend, .reaction { background: #f5f6f7; }
+.previous { color: #65676b; text-decoration: line-through; }
+.attachment { font-size: .9em; }
+pre { white-space: pre-wrap; margin: .3em 0; font-family: inherit; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{len .Records}} records, exported {{time .ExportedAtMs}}</p>
+{{range .Records}}<div class="record {{.Kind}} {{.Direction}}">
+<div class="meta">{{time .TimestampMs}} · {{.Kind}} · sender {{.SenderID}}{{if .IsE2EE}} · end-to-end encrypted{{end}} · <code>{{.MessageID}}</code></div>
+{{if eq .Kind "reaction"}}{{if .Reaction}}Reacted {{.Reaction}}{{else}}Removed reaction{{end}}
+{{else if eq .Kind "unsend"}}Unsent{{if .PreviousText}}: <span class="previous">{{.PreviousText}}</span>{{end}}
+{{else}}{{if .PreviousText}}<pre class="previous">{{.PreviousText}}</pre>{{end}}{{if .Text}}<pre>{{.Text}}</pre>{{end}}
+{{end}}{{if .ReplyTo}}<div class="meta">In reply to <code>{{.ReplyTo.MessageID}}</code></div>{{end}}
+{{range .Attachments}}<div class="attachment">[{{.Type}}{{if .FileName}}: {{.FileName}}{{end}}{{if .MimeType}}, {{.MimeType}}{{end}}{{if .FileSize}}, {{.FileSize}} bytes{{end}}]{{if .URL}} <a href="{{.URL}}">{{.URL}}</a>{{else if .DirectPath}} <code>{{.DirectPath}}</code>{{end}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+func (c *Client) renderArchiveHTML(buf *bytes.Buffer, threadID int64, records []*ArchiveRecord) error {
+	title := fmt.Sprintf("Thread %d", threadID)
+	if thread := c.state.getThread(threadID); thread != nil && thread.Name != "" {
+		title = fmt.Sprintf("%s (%d)", thread.Name, threadID)
+	}
+	return archiveHTMLTemplate.Execute(buf, map[string]interface{}{
+		"Title":        title,
+		"Records":      records,
+		"ExportedAtMs": timeNowMs(),
+	})
+}
@@ -0,0 +1,242 @@
+package bridge
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// DeviceSchemaVersion is the DeviceJSON format written by this version.
+// Version 1 data has no schema_version field.
+const DeviceSchemaVersion = 2
+
+const (
+	deviceBackupFormat     = "messagix-device-backup"
+	deviceBackupVersion    = 1
+	deviceBackupIterations = 600000
+)
+
+// DeviceBackup is an encrypted DeviceJSON snapshot.
+// The key is derived from a passphrase with PBKDF2-SHA256 and the data is sealed with AES-256-GCM.
+type DeviceBackup struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// DeviceSummary describes device data without exposing key material
+type DeviceSummary struct {
+	SchemaVersion     int    `json:"schemaVersion"`
+	JID               string `json:"jid,omitempty"`
+	RegistrationID    uint32 `json:"registrationId"`
+	FacebookUUID      string `json:"facebookUuid,omitempty"`
+	SignedPreKeyID    uint32 `json:"signedPreKeyId"`
+	NextPreKeyID      uint32 `json:"nextPreKeyId"`
+	Sessions          int    `json:"sessions"`
+	Identities        int    `json:"identities"`
+	PendingIdentities int    `json:"pendingIdentities"`
+	PreKeys           int    `json:"preKeys"`
+	SenderKeys        int    `json:"senderKeys"`
+	BufferedEvents    int    `json:"bufferedEvents"`
+}
+
+// DeviceDiff lists the differences between two device snapshots
+type DeviceDiff struct {
+	Fields            []string       `json:"fields,omitempty"` // changed top-level fields
+	Sessions          *DeviceMapDiff `json:"sessions,omitempty"`
+	Identities        *DeviceMapDiff `json:"identities,omitempty"`
+	PendingIdentities *DeviceMapDiff `json:"pendingIdentities,omitempty"`
+	PreKeys           *DeviceMapDiff `json:"preKeys,omitempty"`
+	SenderKeys        *DeviceMapDiff `json:"senderKeys,omitempty"`
+	BufferedEvents    *DeviceMapDiff `json:"bufferedEvents,omitempty"`
+}
+
+// DeviceMapDiff lists added, removed and changed keys of a DeviceJSON map
+type DeviceMapDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty returns true if the snapshots are identical
+func (d *DeviceDiff) Empty() bool {
+	return len(d.Fields) == 0 && d.Sessions == nil && d.Identities == nil && d.PendingIdentities == nil &&
+		d.PreKeys == nil && d.SenderKeys == nil && d.BufferedEvents == nil
+}
+
+// ParseDeviceData parses and validates serialized device data
+func ParseDeviceData(data []byte) (*DeviceJSON, error) {
+	var deviceJSON DeviceJSON
+	if err := json.Unmarshal(data, &deviceJSON); err != nil {
+		return nil, fmt.Errorf("invalid device data: %w", err)
+	}
+	if !newEmptyDeviceStore("").loadJSON(&deviceJSON) {
+		return nil, errors.New("invalid key lengths in device data")
+	}
+	if deviceJSON.SchemaVersion == 0 {
+		deviceJSON.SchemaVersion = 1
+	}
+	if deviceJSON.SchemaVersion > DeviceSchemaVersion {
+		return nil, fmt.Errorf("device data schema version %d is newer than supported version %d", deviceJSON.SchemaVersion, DeviceSchemaVersion)
+	}
+	return &deviceJSON, nil
+}
+
+// SummarizeDevice returns counts and identifiers of parsed device data
+func SummarizeDevice(deviceJSON *DeviceJSON) *DeviceSummary {
+	summary := &DeviceSummary{
+		SchemaVersion:     deviceJSON.SchemaVersion,
+		RegistrationID:    deviceJSON.RegistrationID,
+		FacebookUUID:      deviceJSON.FacebookUUID,
+		SignedPreKeyID:    deviceJSON.SignedPreKeyID,
+		NextPreKeyID:      deviceJSON.NextPreKeyID,
+		Sessions:          len(deviceJSON.Sessions),
+		Identities:        len(deviceJSON.Identities),
+		PendingIdentities: len(deviceJSON.PendingIdentities),
+		PreKeys:           len(deviceJSON.PreKeys),
+		SenderKeys:        len(deviceJSON.SenderKeys),
+		BufferedEvents:    len(deviceJSON.BufferedEvents),
+	}
+	if deviceJSON.JIDUser != "" {
+		summary.JID = waTypes.JID{User: deviceJSON.JIDUser, Device: deviceJSON.JIDDevice, Server: waTypes.MessengerServer}.String()
+	}
+	return summary
+}
+
+// DiffDevices compares two parsed device snapshots
+func DiffDevices(a, b *DeviceJSON) *DeviceDiff {
+	diff := &DeviceDiff{}
+	check := func(name string, changed bool) {
+		if changed {
+			diff.Fields = append(diff.Fields, name)
+		}
+	}
+	check("schema_version", a.SchemaVersion != b.SchemaVersion)
+	check("noise_key", a.NoiseKeyPriv != b.NoiseKeyPriv)
+	check("identity_key", a.IdentityKeyPriv != b.IdentityKeyPriv)
+	check("signed_pre_key", a.SignedPreKeyID != b.SignedPreKeyID || a.SignedPreKeyPriv != b.SignedPreKeyPriv)
+	check("registration_id", a.RegistrationID != b.RegistrationID)
+	check("adv_secret_key", a.AdvSecretKey != b.AdvSecretKey)
+	check("facebook_uuid", a.FacebookUUID != b.FacebookUUID)
+	check("jid", a.JIDUser != b.JIDUser || a.JIDDevice != b.JIDDevice)
+	check("next_pre_key_id", a.NextPreKeyID != b.NextPreKeyID)
+	check("uploaded_pre_key_id", (a.UploadedPreKeyID == nil) != (b.UploadedPreKeyID == nil) ||
+		(a.UploadedPreKeyID != nil && *a.UploadedPreKeyID != *b.UploadedPreKeyID))
+
+	diff.Sessions = diffMaps(a.Sessions, b.Sessions)
+	diff.Identities = diffMaps(a.Identities, b.Identities)
+	diff.PendingIdentities = diffMaps(a.PendingIdentities, b.PendingIdentities)
+	diff.PreKeys = diffMaps(a.PreKeys, b.PreKeys)
+	diff.SenderKeys = diffMaps(a.SenderKeys, b.SenderKeys)
+	diff.BufferedEvents = diffMaps(a.BufferedEvents, b.BufferedEvents)
+	return diff
+}
+
+// diffMaps compares two maps by key and value, returning nil if they're equal
+func diffMaps[V comparable](a, b map[string]V) *DeviceMapDiff {
+	diff := &DeviceMapDiff{}
+	for k, v := range a {
+		if other, ok := b[k]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		} else if other != v {
+			diff.Changed = append(diff.Changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			diff.Added = append(diff.Added, k)
+		}
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		return nil
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// EncryptDeviceBackup validates device data and encrypts it with a passphrase
+func EncryptDeviceBackup(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase is required")
+	}
+	if _, err := ParseDeviceData(data); err != nil {
+		return nil, err
+	}
+
+	backup := &DeviceBackup{
+		Format:     deviceBackupFormat,
+		Version:    deviceBackupVersion,
+		KDF:        "pbkdf2-sha256",
+		Iterations: deviceBackupIterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(backup.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := backup.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	backup.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(backup.Nonce); err != nil {
+		return nil, err
+	}
+	backup.Ciphertext = aead.Seal(nil, backup.Nonce, data, []byte(deviceBackupFormat))
+	return json.MarshalIndent(backup, "", "  ")
+}
+
+// DecryptDeviceBackup decrypts a backup and validates the contained device data
+func DecryptDeviceBackup(backupData []byte, passphrase string) ([]byte, error) {
+	var backup DeviceBackup
+	if err := json.Unmarshal(backupData, &backup); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	if backup.Format != deviceBackupFormat {
+		return nil, fmt.Errorf("not a device backup (format %q)", backup.Format)
+	}
+	if backup.Version != deviceBackupVersion || backup.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("unsupported backup version %d (%s)", backup.Version, backup.KDF)
+	}
+	aead, err := backup.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(backup.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid backup nonce")
+	}
+	data, err := aead.Open(nil, backup.Nonce, backup.Ciphertext, []byte(deviceBackupFormat))
+	if err != nil {
+		return nil, errors.New("failed to decrypt backup: wrong passphrase or corrupted data")
+	}
+	if _, err := ParseDeviceData(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cipher derives the AES-GCM cipher for the backup
+func (b *DeviceBackup) cipher(passphrase string) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, b.Salt, b.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
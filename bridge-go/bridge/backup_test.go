@@ -0,0 +1,101 @@
+package bridge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testDeviceData(t *testing.T) []byte {
+	t.Helper()
+	ds := newEmptyDeviceStore("")
+	ds.generateDevice()
+	data, err := ds.GetDeviceData()
+	if err != nil {
+		t.Fatalf("GetDeviceData: %v", err)
+	}
+	return []byte(data)
+}
+
+func TestDeviceBackupRoundTrip(t *testing.T) {
+	data := testDeviceData(t)
+	backup, err := EncryptDeviceBackup(data, "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptDeviceBackup: %v", err)
+	}
+	if strings.Contains(string(backup), string(data)) {
+		t.Fatal("backup contains the plaintext device data")
+	}
+	decrypted, err := DecryptDeviceBackup(backup, "correct horse")
+	if err != nil {
+		t.Fatalf("DecryptDeviceBackup: %v", err)
+	}
+	if string(decrypted) != string(data) {
+		t.Fatal("decrypted data differs from the original")
+	}
+}
+
+func TestEncryptDeviceBackupErrors(t *testing.T) {
+	data := testDeviceData(t)
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    string
+	}{
+		{"empty passphrase", data, "", "passphrase is required"},
+		{"not json", []byte("{"), "pass", "invalid device data"},
+		{"bad key lengths", []byte(`{"noise_key_priv":"AAEC"}`), "pass", "invalid key lengths"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncryptDeviceBackup(tt.data, tt.passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecryptDeviceBackupErrors(t *testing.T) {
+	backup, err := EncryptDeviceBackup(testDeviceData(t), "pass")
+	if err != nil {
+		t.Fatalf("EncryptDeviceBackup: %v", err)
+	}
+	modify := func(fn func(*DeviceBackup)) []byte {
+		var b DeviceBackup
+		if err := json.Unmarshal(backup, &b); err != nil {
+			t.Fatalf("unmarshal backup: %v", err)
+		}
+		fn(&b)
+		out, err := json.Marshal(&b)
+		if err != nil {
+			t.Fatalf("marshal backup: %v", err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		backup     []byte
+		passphrase string
+		wantErr    string
+	}{
+		{"wrong passphrase", backup, "wrong", "wrong passphrase"},
+		{"not json", []byte("nope"), "pass", "invalid backup"},
+		{"other format", modify(func(b *DeviceBackup) { b.Format = "other" }), "pass", "not a device backup"},
+		{"newer version", modify(func(b *DeviceBackup) { b.Version = 2 }), "pass", "unsupported backup version"},
+		{"other kdf", modify(func(b *DeviceBackup) { b.KDF = "scrypt" }), "pass", "unsupported backup version"},
+		{"short nonce", modify(func(b *DeviceBackup) { b.Nonce = b.Nonce[:4] }), "pass", "invalid backup nonce"},
+		{"tampered ciphertext", modify(func(b *DeviceBackup) { b.Ciphertext[0] ^= 1 }), "pass", "corrupted data"},
+		{"tampered salt", modify(func(b *DeviceBackup) { b.Salt[0] ^= 1 }), "pass", "corrupted data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecryptDeviceBackup(tt.backup, tt.passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,12 +3,15 @@ package bridge
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -41,6 +44,20 @@ type Client struct {
 	mu                  sync.RWMutex
 	recentUnreactions   map[string]int64 // key: messageId+actorId, value: timestamp
 	recentUnreactionsMu sync.RWMutex
+	preKeyMaintenance   sync.Once
+	state               *stateMirror
+	archive             *messageArchive // nil unless ArchivePath is set
+	sends               *sendTracker
+	ids                 idGenerator
+	idempotency         *idempotencyCache
+	outbox              *outbox
+	outboxWorker        sync.Once
+	limiter             *rateLimiter // nil unless rate limiting is configured
+	scheduler           *scheduler
+	schedulerWorker     sync.Once
+	autoRead            bool
+	e2eeReads           e2eeReadBatcher
+	threads             *threadRegistry
 }
 
 // ClientConfig for creating a new client
@@ -51,6 +68,25 @@ type ClientConfig struct {
 	DeviceData     string            `json:"deviceData,omitempty"`     // JSON string of device data (optional, takes priority over DevicePath)
 	E2EEMemoryOnly bool              `json:"e2eeMemoryOnly,omitempty"` // If true, E2EE state is stored in memory only (no file, no events)
 	LogLevel       string            `json:"logLevel"`
+	// IdentityTrustPolicy controls changed E2EE identity keys: "tofu" (default), "always" or "manual"
+	IdentityTrustPolicy string `json:"identityTrustPolicy,omitempty"`
+	// StateMessagesPerThread is how many recent messages per thread the state mirror keeps (default 100)
+	StateMessagesPerThread int `json:"stateMessagesPerThread,omitempty"`
+	// ArchivePath enables the local message archive, one JSONL file per thread in this directory
+	ArchivePath string `json:"archivePath,omitempty"`
+	// IdempotencyWindowSeconds is how long results of sends with an idempotency key are remembered (default 24h)
+	IdempotencyWindowSeconds int `json:"idempotencyWindowSeconds,omitempty"`
+	// OutboxPath persists queued sends to this JSON file so they survive restarts (memory only if empty).
+	// Payloads are kept in the "<OutboxPath>.payloads" directory until their item is finished.
+	OutboxPath string `json:"outboxPath,omitempty"`
+	// RateLimit throttles outgoing messages, reactions and typing (off if unset, "{}" uses the default budgets)
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+	// ScheduledMessagesPath persists scheduled messages to this JSON file (memory only if empty)
+	ScheduledMessagesPath string `json:"scheduledMessagesPath,omitempty"`
+	// AutoMarkRead sends read receipts for incoming regular and E2EE messages as soon as
+	// they're received, whether or not the app has handled them yet, and makes E2EE
+	// delivery receipts visible to senders
+	AutoMarkRead bool `json:"autoMarkRead,omitempty"`
 }
 
 // NewClient creates a new messagix client
@@ -98,9 +134,35 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 		ClientSettings: exhttp.ClientSettings{},
 	})
 
+	trustPolicy, err := parseIdentityTrustPolicy(cfg.IdentityTrustPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive *messageArchive
+	if cfg.ArchivePath != "" {
+		if archive, err = newMessageArchive(cfg.ArchivePath); err != nil {
+			return nil, err
+		}
+	}
+
+	outbox, err := newOutbox(cfg.OutboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := newRateLimiter(cfg.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduler, err := newScheduler(cfg.ScheduledMessagesPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create device store
 	var deviceStore *DeviceStore
-	var err error
 	if cfg.E2EEMemoryOnly {
 		// Memory only mode - no persistence
 		deviceStore, err = NewDeviceStoreMemoryOnly()
@@ -118,6 +180,7 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	deviceStore.trustPolicy = trustPolicy
 
 	// Set device on client
 	msgClient.SetDevice(deviceStore.Device)
@@ -133,6 +196,15 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 		ctx:               ctx,
 		cancel:            cancel,
 		recentUnreactions: make(map[string]int64),
+		state:             newStateMirror(cfg.StateMessagesPerThread),
+		archive:           archive,
+		sends:             newSendTracker(),
+		idempotency:       newIdempotencyCache(time.Duration(cfg.IdempotencyWindowSeconds) * time.Second),
+		outbox:            outbox,
+		limiter:           limiter,
+		scheduler:         scheduler,
+		autoRead:          cfg.AutoMarkRead,
+		threads:           newThreadRegistry(),
 	}
 
 	// Set callback for device data changes (only when using deviceData mode)
@@ -144,6 +216,10 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 		}
 	}
 
+	deviceStore.onIdentityChanged = func(evt *IdentityChangedEvent) {
+		client.emitEvent(EventTypeIdentityChanged, evt)
+	}
+
 	// Set event handler
 	msgClient.SetEventHandler(client.handleEvent)
 
@@ -174,14 +250,23 @@ func (c *Client) Connect() (*UserInfo, *InitialData, error) {
 	// Extract initial data
 	initialData := &InitialData{}
 	if initialTable != nil {
-		for _, t := range initialTable.LSDeleteThenInsertThread {
-			initialData.Threads = append(initialData.Threads, convertThread(t))
-		}
+		initialData.Threads = c.threadsFromTable(initialTable)
 		for _, m := range initialTable.LSUpsertMessage {
 			initialData.Messages = append(initialData.Messages, convertMessage(m))
 		}
+		c.state.applyTable(initialTable, initialData.Threads, initialData.Messages)
 	}
 
+	// Deliver sends queued while disconnected, and any queued from now on
+	c.outboxWorker.Do(func() {
+		go c.runOutbox()
+	})
+	c.outbox.notify()
+	c.schedulerWorker.Do(func() {
+		go c.runScheduler()
+	})
+	c.scheduler.notify()
+
 	return userInfo, initialData, nil
 }
 
@@ -197,6 +282,12 @@ func (c *Client) ConnectE2EE() error {
 		return err
 	}
 	c.E2EE = e2eeClient
+	// Buffer decrypted plaintexts so retried or replayed ciphertexts are deduplicated
+	c.E2EE.EnableDecryptedEventBuffer = true
+	// Under the manual policy, changed identities must stay untrusted until approved
+	c.E2EE.AutoTrustIdentity = c.DeviceStore.trustPolicy != IdentityTrustManual
+	// Delivery receipts are "inactive" and not shown to senders unless forced
+	c.E2EE.SetForceActiveDeliveryReceipts(c.autoRead)
 
 	// Register E2EE
 	if err := c.Messagix.RegisterE2EE(c.ctx, c.FBID); err != nil {
@@ -212,6 +303,11 @@ func (c *Client) ConnectE2EE() error {
 	// Add E2EE event handler
 	c.E2EE.AddEventHandler(c.handleE2EEEvent)
 
+	// Keep prekeys replenished for long-running sessions
+	c.preKeyMaintenance.Do(func() {
+		go c.runPreKeyMaintenance()
+	})
+
 	return nil
 }
 
@@ -242,133 +338,208 @@ func (c *Client) Events() <-chan *Event {
 
 // DeviceStore manages the E2EE device persistently
 type DeviceStore struct {
-	Device        *store.Device
-	path          string
-	mu            sync.RWMutex
-	identities    map[string][32]byte
-	sessions      map[string][]byte
-	preKeys       map[uint32]*keys.PreKey
-	senderKeys    map[string][]byte
-	nextPreKeyID  uint32
-	onDataChanged func(string) // callback when data changes (for deviceData mode)
+	Device         *store.Device
+	path           string
+	mu             sync.RWMutex
+	decryptionMu   sync.Mutex // serializes DoDecryptionTxn callers
+	identities     map[string][32]byte
+	sessions       map[string][]byte
+	preKeys        map[uint32]*keys.PreKey
+	senderKeys     map[string][]byte
+	bufferedEvents map[[32]byte]*store.BufferedEvent
+	nextPreKeyID   uint32
+	onDataChanged  func(string) // callback when data changes (for deviceData mode)
+
+	uploadedPreKeyID uint32 // highest prekey ID known to be on the server
+
+	trustPolicy       IdentityTrustPolicy
+	pendingIdentities map[string][32]byte         // changed keys not yet trusted
+	onIdentityChanged func(*IdentityChangedEvent) // callback when a contact's identity key changes
 }
 
 // DeviceJSON for JSON serialization
 type DeviceJSON struct {
-	NoiseKeyPriv     string            `json:"noise_key_priv"`
-	IdentityKeyPriv  string            `json:"identity_key_priv"`
-	SignedPreKeyPriv string            `json:"signed_pre_key_priv"`
-	SignedPreKeyID   uint32            `json:"signed_pre_key_id"`
-	SignedPreKeySig  string            `json:"signed_pre_key_sig"`
-	RegistrationID   uint32            `json:"registration_id"`
-	AdvSecretKey     string            `json:"adv_secret_key"`
-	FacebookUUID     string            `json:"facebook_uuid"`
-	JIDUser          string            `json:"jid_user,omitempty"`
-	JIDDevice        uint16            `json:"jid_device,omitempty"`
-	Identities       map[string]string `json:"identities,omitempty"`
-	Sessions         map[string]string `json:"sessions,omitempty"`
-	PreKeys          map[string]string `json:"pre_keys,omitempty"`
-	SenderKeys       map[string]string `json:"sender_keys,omitempty"`
-	NextPreKeyID     uint32            `json:"next_pre_key_id"`
+	SchemaVersion     int                          `json:"schema_version,omitempty"` // missing in version 1 data
+	NoiseKeyPriv      string                       `json:"noise_key_priv"`
+	IdentityKeyPriv   string                       `json:"identity_key_priv"`
+	SignedPreKeyPriv  string                       `json:"signed_pre_key_priv"`
+	SignedPreKeyID    uint32                       `json:"signed_pre_key_id"`
+	SignedPreKeySig   string                       `json:"signed_pre_key_sig"`
+	RegistrationID    uint32                       `json:"registration_id"`
+	AdvSecretKey      string                       `json:"adv_secret_key"`
+	FacebookUUID      string                       `json:"facebook_uuid"`
+	JIDUser           string                       `json:"jid_user,omitempty"`
+	JIDDevice         uint16                       `json:"jid_device,omitempty"`
+	Identities        map[string]string            `json:"identities,omitempty"`
+	PendingIdentities map[string]string            `json:"pending_identities,omitempty"`
+	Sessions          map[string]string            `json:"sessions,omitempty"`
+	PreKeys           map[string]string            `json:"pre_keys,omitempty"`
+	SenderKeys        map[string]string            `json:"sender_keys,omitempty"`
+	BufferedEvents    map[string]BufferedEventJSON `json:"buffered_events,omitempty"` // key: hex ciphertext hash
+	NextPreKeyID      uint32                       `json:"next_pre_key_id"`
+	UploadedPreKeyID  *uint32                      `json:"uploaded_pre_key_id,omitempty"`
+}
+
+// BufferedEventJSON is the serialized form of a decrypted event buffer entry
+type BufferedEventJSON struct {
+	Plaintext  string `json:"plaintext,omitempty"` // empty once the event has been handled
+	InsertTime int64  `json:"insert_time"`         // unix milliseconds
+	ServerTime int64  `json:"server_time"`         // unix seconds
+}
+
+// newEmptyDeviceStore allocates a device store with empty state maps
+func newEmptyDeviceStore(path string) *DeviceStore {
+	return &DeviceStore{
+		path:           path,
+		identities:     make(map[string][32]byte),
+		sessions:       make(map[string][]byte),
+		preKeys:        make(map[uint32]*keys.PreKey),
+		senderKeys:     make(map[string][]byte),
+		bufferedEvents: make(map[[32]byte]*store.BufferedEvent),
+		nextPreKeyID:   1,
+
+		trustPolicy:       IdentityTrustTOFU,
+		pendingIdentities: make(map[string][32]byte),
+	}
 }
 
 // NewDeviceStore creates or loads a device store
 func NewDeviceStore(path string) (*DeviceStore, error) {
-	ds := &DeviceStore{
-		path:         path,
-		identities:   make(map[string][32]byte),
-		sessions:     make(map[string][]byte),
-		preKeys:      make(map[uint32]*keys.PreKey),
-		senderKeys:   make(map[string][]byte),
-		nextPreKeyID: 1,
-	}
+	ds := newEmptyDeviceStore(path)
 
 	if data, err := os.ReadFile(path); err == nil {
 		var deviceJSON DeviceJSON
 		if err := json.Unmarshal(data, &deviceJSON); err != nil {
 			return nil, err
 		}
-
-		noisePriv, _ := base64.StdEncoding.DecodeString(deviceJSON.NoiseKeyPriv)
-		identityPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.IdentityKeyPriv)
-		signedPreKeyPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeyPriv)
-		signedPreKeySig, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeySig)
-		advSecretKey, _ := base64.StdEncoding.DecodeString(deviceJSON.AdvSecretKey)
-
-		if len(noisePriv) != 32 || len(identityPriv) != 32 || len(signedPreKeyPriv) != 32 || len(signedPreKeySig) != 64 {
+		if !ds.loadJSON(&deviceJSON) {
 			return nil, errors.New("invalid key lengths in stored device")
 		}
-
-		ds.Device = &store.Device{
-			NoiseKey:    keys.NewKeyPairFromPrivateKey(*(*[32]byte)(noisePriv)),
-			IdentityKey: keys.NewKeyPairFromPrivateKey(*(*[32]byte)(identityPriv)),
-			SignedPreKey: &keys.PreKey{
-				KeyPair:   *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(signedPreKeyPriv)),
-				KeyID:     deviceJSON.SignedPreKeyID,
-				Signature: (*[64]byte)(signedPreKeySig),
-			},
-			RegistrationID: deviceJSON.RegistrationID,
-			AdvSecretKey:   advSecretKey,
+	} else {
+		// Create new device
+		ds.generateDevice()
+		if err := ds.Save(); err != nil {
+			return nil, err
 		}
+	}
 
-		if deviceJSON.FacebookUUID != "" {
-			ds.Device.FacebookUUID, _ = uuid.Parse(deviceJSON.FacebookUUID)
-		}
-		if deviceJSON.JIDUser != "" {
-			ds.Device.ID = &waTypes.JID{User: deviceJSON.JIDUser, Device: deviceJSON.JIDDevice, Server: waTypes.MessengerServer}
-		}
+	ds.attachStores()
+	return ds, nil
+}
 
-		ds.nextPreKeyID = deviceJSON.NextPreKeyID
+// generateDevice creates fresh device keys
+func (ds *DeviceStore) generateDevice() {
+	ds.Device = &store.Device{
+		NoiseKey:       keys.NewKeyPair(),
+		IdentityKey:    keys.NewKeyPair(),
+		RegistrationID: rand.Uint32()%16380 + 1,
+		AdvSecretKey:   make([]byte, 32),
+	}
+	rand.Read(ds.Device.AdvSecretKey)
+	ds.Device.SignedPreKey = ds.Device.IdentityKey.CreateSignedPreKey(1)
+	ds.Device.FacebookUUID = uuid.New()
+}
 
-		// Load identities
-		for k, v := range deviceJSON.Identities {
-			decoded, _ := base64.StdEncoding.DecodeString(v)
-			if len(decoded) == 32 {
-				ds.identities[k] = *(*[32]byte)(decoded)
-			}
-		}
+// loadJSON populates the store from serialized device data.
+// Returns false if the stored keys have invalid lengths.
+func (ds *DeviceStore) loadJSON(deviceJSON *DeviceJSON) bool {
+	noisePriv, _ := base64.StdEncoding.DecodeString(deviceJSON.NoiseKeyPriv)
+	identityPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.IdentityKeyPriv)
+	signedPreKeyPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeyPriv)
+	signedPreKeySig, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeySig)
+	advSecretKey, _ := base64.StdEncoding.DecodeString(deviceJSON.AdvSecretKey)
 
-		// Load sessions
-		for k, v := range deviceJSON.Sessions {
-			decoded, _ := base64.StdEncoding.DecodeString(v)
-			ds.sessions[k] = decoded
+	if len(noisePriv) != 32 || len(identityPriv) != 32 || len(signedPreKeyPriv) != 32 || len(signedPreKeySig) != 64 {
+		return false
+	}
+
+	ds.Device = &store.Device{
+		NoiseKey:    keys.NewKeyPairFromPrivateKey(*(*[32]byte)(noisePriv)),
+		IdentityKey: keys.NewKeyPairFromPrivateKey(*(*[32]byte)(identityPriv)),
+		SignedPreKey: &keys.PreKey{
+			KeyPair:   *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(signedPreKeyPriv)),
+			KeyID:     deviceJSON.SignedPreKeyID,
+			Signature: (*[64]byte)(signedPreKeySig),
+		},
+		RegistrationID: deviceJSON.RegistrationID,
+		AdvSecretKey:   advSecretKey,
+	}
+
+	if deviceJSON.FacebookUUID != "" {
+		ds.Device.FacebookUUID, _ = uuid.Parse(deviceJSON.FacebookUUID)
+	}
+	if deviceJSON.JIDUser != "" {
+		ds.Device.ID = &waTypes.JID{User: deviceJSON.JIDUser, Device: deviceJSON.JIDDevice, Server: waTypes.MessengerServer}
+	}
+
+	ds.nextPreKeyID = deviceJSON.NextPreKeyID
+	if deviceJSON.UploadedPreKeyID != nil {
+		ds.uploadedPreKeyID = *deviceJSON.UploadedPreKeyID
+	} else if ds.nextPreKeyID > 1 {
+		// Data from before upload tracking: every generated prekey went through an upload
+		ds.uploadedPreKeyID = ds.nextPreKeyID - 1
+	}
+
+	// Load identities
+	for k, v := range deviceJSON.Identities {
+		decoded, _ := base64.StdEncoding.DecodeString(v)
+		if len(decoded) == 32 {
+			ds.identities[k] = *(*[32]byte)(decoded)
 		}
+	}
+	for k, v := range deviceJSON.PendingIdentities {
+		decoded, _ := base64.StdEncoding.DecodeString(v)
+		if len(decoded) == 32 {
+			ds.pendingIdentities[k] = *(*[32]byte)(decoded)
+		}
+	}
+
+	// Load sessions
+	for k, v := range deviceJSON.Sessions {
+		decoded, _ := base64.StdEncoding.DecodeString(v)
+		ds.sessions[k] = decoded
+	}
 
-		// Load pre-keys
-		for idStr, v := range deviceJSON.PreKeys {
-			var id uint32
-			fmt.Sscanf(idStr, "%d", &id)
-			decoded, _ := base64.StdEncoding.DecodeString(v)
-			if len(decoded) == 32 {
-				ds.preKeys[id] = &keys.PreKey{
-					KeyPair: *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(decoded)),
-					KeyID:   id,
-				}
+	// Load pre-keys
+	for idStr, v := range deviceJSON.PreKeys {
+		var id uint32
+		fmt.Sscanf(idStr, "%d", &id)
+		decoded, _ := base64.StdEncoding.DecodeString(v)
+		if len(decoded) == 32 {
+			ds.preKeys[id] = &keys.PreKey{
+				KeyPair: *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(decoded)),
+				KeyID:   id,
 			}
 		}
+	}
 
-		// Load sender keys
-		for k, v := range deviceJSON.SenderKeys {
-			decoded, _ := base64.StdEncoding.DecodeString(v)
-			ds.senderKeys[k] = decoded
+	// Load sender keys
+	for k, v := range deviceJSON.SenderKeys {
+		decoded, _ := base64.StdEncoding.DecodeString(v)
+		ds.senderKeys[k] = decoded
+	}
+
+	// Load buffered events
+	for hashHex, v := range deviceJSON.BufferedEvents {
+		hash, err := hex.DecodeString(hashHex)
+		if err != nil || len(hash) != 32 {
+			continue
 		}
-	} else {
-		// Create new device
-		ds.Device = &store.Device{
-			NoiseKey:       keys.NewKeyPair(),
-			IdentityKey:    keys.NewKeyPair(),
-			RegistrationID: rand.Uint32()%16380 + 1,
-			AdvSecretKey:   make([]byte, 32),
+		buf := &store.BufferedEvent{
+			InsertTime: time.UnixMilli(v.InsertTime),
+			ServerTime: time.Unix(v.ServerTime, 0),
 		}
-		rand.Read(ds.Device.AdvSecretKey)
-		ds.Device.SignedPreKey = ds.Device.IdentityKey.CreateSignedPreKey(1)
-		ds.Device.FacebookUUID = uuid.New()
-		if err := ds.Save(); err != nil {
-			return nil, err
+		if v.Plaintext != "" {
+			buf.Plaintext, _ = base64.StdEncoding.DecodeString(v.Plaintext)
 		}
+		ds.bufferedEvents[*(*[32]byte)(hash)] = buf
 	}
 
-	// Set store interfaces
+	return true
+}
+
+// attachStores wires the device store into the whatsmeow device
+func (ds *DeviceStore) attachStores() {
 	ds.Device.Identities = ds
 	ds.Device.Sessions = ds
 	ds.Device.PreKeys = ds
@@ -390,8 +561,6 @@ func NewDeviceStore(path string) (*DeviceStore, error) {
 			AccountSignature: make([]byte, 64), DeviceSignature: make([]byte, 64),
 		}
 	}
-
-	return ds, nil
 }
 
 // GetDeviceData returns the device data as a JSON string
@@ -399,20 +568,25 @@ func (ds *DeviceStore) GetDeviceData() (string, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
 
+	uploadedPreKeyID := ds.uploadedPreKeyID
 	deviceJSON := DeviceJSON{
-		NoiseKeyPriv:     base64.StdEncoding.EncodeToString(ds.Device.NoiseKey.Priv[:]),
-		IdentityKeyPriv:  base64.StdEncoding.EncodeToString(ds.Device.IdentityKey.Priv[:]),
-		SignedPreKeyPriv: base64.StdEncoding.EncodeToString(ds.Device.SignedPreKey.Priv[:]),
-		SignedPreKeyID:   ds.Device.SignedPreKey.KeyID,
-		SignedPreKeySig:  base64.StdEncoding.EncodeToString(ds.Device.SignedPreKey.Signature[:]),
-		RegistrationID:   ds.Device.RegistrationID,
-		AdvSecretKey:     base64.StdEncoding.EncodeToString(ds.Device.AdvSecretKey),
-		FacebookUUID:     ds.Device.FacebookUUID.String(),
-		NextPreKeyID:     ds.nextPreKeyID,
-		Identities:       make(map[string]string),
-		Sessions:         make(map[string]string),
-		PreKeys:          make(map[string]string),
-		SenderKeys:       make(map[string]string),
+		SchemaVersion:     DeviceSchemaVersion,
+		NoiseKeyPriv:      base64.StdEncoding.EncodeToString(ds.Device.NoiseKey.Priv[:]),
+		IdentityKeyPriv:   base64.StdEncoding.EncodeToString(ds.Device.IdentityKey.Priv[:]),
+		SignedPreKeyPriv:  base64.StdEncoding.EncodeToString(ds.Device.SignedPreKey.Priv[:]),
+		SignedPreKeyID:    ds.Device.SignedPreKey.KeyID,
+		SignedPreKeySig:   base64.StdEncoding.EncodeToString(ds.Device.SignedPreKey.Signature[:]),
+		RegistrationID:    ds.Device.RegistrationID,
+		AdvSecretKey:      base64.StdEncoding.EncodeToString(ds.Device.AdvSecretKey),
+		FacebookUUID:      ds.Device.FacebookUUID.String(),
+		NextPreKeyID:      ds.nextPreKeyID,
+		UploadedPreKeyID:  &uploadedPreKeyID,
+		Identities:        make(map[string]string),
+		PendingIdentities: make(map[string]string),
+		Sessions:          make(map[string]string),
+		PreKeys:           make(map[string]string),
+		SenderKeys:        make(map[string]string),
+		BufferedEvents:    make(map[string]BufferedEventJSON),
 	}
 
 	if ds.Device.ID != nil {
@@ -424,6 +598,9 @@ func (ds *DeviceStore) GetDeviceData() (string, error) {
 	for k, v := range ds.identities {
 		deviceJSON.Identities[k] = base64.StdEncoding.EncodeToString(v[:])
 	}
+	for k, v := range ds.pendingIdentities {
+		deviceJSON.PendingIdentities[k] = base64.StdEncoding.EncodeToString(v[:])
+	}
 
 	// Save sessions
 	for k, v := range ds.sessions {
@@ -440,6 +617,18 @@ func (ds *DeviceStore) GetDeviceData() (string, error) {
 		deviceJSON.SenderKeys[k] = base64.StdEncoding.EncodeToString(v)
 	}
 
+	// Save buffered events
+	for hash, buf := range ds.bufferedEvents {
+		entry := BufferedEventJSON{
+			InsertTime: buf.InsertTime.UnixMilli(),
+			ServerTime: buf.ServerTime.Unix(),
+		}
+		if buf.Plaintext != nil {
+			entry.Plaintext = base64.StdEncoding.EncodeToString(buf.Plaintext)
+		}
+		deviceJSON.BufferedEvents[hex.EncodeToString(hash[:])] = entry
+	}
+
 	data, err := json.MarshalIndent(deviceJSON, "", "  ")
 	if err != nil {
 		return "", err
@@ -467,157 +656,28 @@ func (ds *DeviceStore) Save() error {
 
 // NewDeviceStoreFromData creates a device store from JSON data string (no file I/O)
 func NewDeviceStoreFromData(dataStr string) (*DeviceStore, error) {
-	ds := &DeviceStore{
-		path:         "", // Empty path means no file saving
-		identities:   make(map[string][32]byte),
-		sessions:     make(map[string][]byte),
-		preKeys:      make(map[uint32]*keys.PreKey),
-		senderKeys:   make(map[string][]byte),
-		nextPreKeyID: 1,
-	}
+	ds := newEmptyDeviceStore("") // Empty path means no file saving
 
 	var deviceJSON DeviceJSON
 	if err := json.Unmarshal([]byte(dataStr), &deviceJSON); err != nil {
 		return nil, err
 	}
-
-	noisePriv, _ := base64.StdEncoding.DecodeString(deviceJSON.NoiseKeyPriv)
-	identityPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.IdentityKeyPriv)
-	signedPreKeyPriv, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeyPriv)
-	signedPreKeySig, _ := base64.StdEncoding.DecodeString(deviceJSON.SignedPreKeySig)
-	advSecretKey, _ := base64.StdEncoding.DecodeString(deviceJSON.AdvSecretKey)
-
-	if len(noisePriv) != 32 || len(identityPriv) != 32 || len(signedPreKeyPriv) != 32 || len(signedPreKeySig) != 64 {
+	if !ds.loadJSON(&deviceJSON) {
 		return nil, errors.New("invalid key lengths in device data")
 	}
 
-	ds.Device = &store.Device{
-		NoiseKey:    keys.NewKeyPairFromPrivateKey(*(*[32]byte)(noisePriv)),
-		IdentityKey: keys.NewKeyPairFromPrivateKey(*(*[32]byte)(identityPriv)),
-		SignedPreKey: &keys.PreKey{
-			KeyPair:   *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(signedPreKeyPriv)),
-			KeyID:     deviceJSON.SignedPreKeyID,
-			Signature: (*[64]byte)(signedPreKeySig),
-		},
-		RegistrationID: deviceJSON.RegistrationID,
-		AdvSecretKey:   advSecretKey,
-	}
-
-	if deviceJSON.FacebookUUID != "" {
-		ds.Device.FacebookUUID, _ = uuid.Parse(deviceJSON.FacebookUUID)
-	}
-	if deviceJSON.JIDUser != "" {
-		ds.Device.ID = &waTypes.JID{User: deviceJSON.JIDUser, Device: deviceJSON.JIDDevice, Server: waTypes.MessengerServer}
-	}
-
-	ds.nextPreKeyID = deviceJSON.NextPreKeyID
-
-	// Load identities
-	for k, v := range deviceJSON.Identities {
-		decoded, _ := base64.StdEncoding.DecodeString(v)
-		if len(decoded) == 32 {
-			ds.identities[k] = *(*[32]byte)(decoded)
-		}
-	}
-
-	// Load sessions
-	for k, v := range deviceJSON.Sessions {
-		decoded, _ := base64.StdEncoding.DecodeString(v)
-		ds.sessions[k] = decoded
-	}
-
-	// Load pre-keys
-	for idStr, v := range deviceJSON.PreKeys {
-		var id uint32
-		fmt.Sscanf(idStr, "%d", &id)
-		decoded, _ := base64.StdEncoding.DecodeString(v)
-		if len(decoded) == 32 {
-			ds.preKeys[id] = &keys.PreKey{
-				KeyPair: *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(decoded)),
-				KeyID:   id,
-			}
-		}
-	}
-
-	// Load sender keys
-	for k, v := range deviceJSON.SenderKeys {
-		decoded, _ := base64.StdEncoding.DecodeString(v)
-		ds.senderKeys[k] = decoded
-	}
-
-	// Set store interfaces
-	ds.Device.Identities = ds
-	ds.Device.Sessions = ds
-	ds.Device.PreKeys = ds
-	ds.Device.SenderKeys = ds
-	ds.Device.Container = ds
-	ds.Device.AppStateKeys = ds
-	ds.Device.AppState = ds
-	ds.Device.Contacts = ds
-	ds.Device.ChatSettings = ds
-	ds.Device.MsgSecrets = ds
-	ds.Device.PrivacyTokens = ds
-	ds.Device.LIDs = ds
-	ds.Device.EventBuffer = ds
-	ds.Device.Initialized = true
-
-	if ds.Device.Account == nil {
-		ds.Device.Account = &waAdv.ADVSignedDeviceIdentity{
-			Details: make([]byte, 0), AccountSignatureKey: make([]byte, 32),
-			AccountSignature: make([]byte, 64), DeviceSignature: make([]byte, 64),
-		}
-	}
-
+	ds.attachStores()
 	return ds, nil
 }
 
 // NewDeviceStoreMemoryOnly creates a new device store that only lives in memory
 // No file saving, no events emitted - state is lost when client disconnects
 func NewDeviceStoreMemoryOnly() (*DeviceStore, error) {
-	ds := &DeviceStore{
-		path:         "", // Empty path means no file saving
-		identities:   make(map[string][32]byte),
-		sessions:     make(map[string][]byte),
-		preKeys:      make(map[uint32]*keys.PreKey),
-		senderKeys:   make(map[string][]byte),
-		nextPreKeyID: 1,
-		// onDataChanged is nil - no callback
-	}
-
-	// Create new device
-	ds.Device = &store.Device{
-		NoiseKey:       keys.NewKeyPair(),
-		IdentityKey:    keys.NewKeyPair(),
-		RegistrationID: rand.Uint32()%16380 + 1,
-		AdvSecretKey:   make([]byte, 32),
-	}
-	rand.Read(ds.Device.AdvSecretKey)
-	ds.Device.SignedPreKey = ds.Device.IdentityKey.CreateSignedPreKey(1)
-	ds.Device.FacebookUUID = uuid.New()
-
-	// Set store interfaces
-	ds.Device.Identities = ds
-	ds.Device.Sessions = ds
-	ds.Device.PreKeys = ds
-	ds.Device.SenderKeys = ds
-	ds.Device.Container = ds
-	ds.Device.AppStateKeys = ds
-	ds.Device.AppState = ds
-	ds.Device.Contacts = ds
-	ds.Device.ChatSettings = ds
-	ds.Device.MsgSecrets = ds
-	ds.Device.PrivacyTokens = ds
-	ds.Device.LIDs = ds
-	ds.Device.EventBuffer = ds
-	ds.Device.Initialized = true
-
-	if ds.Device.Account == nil {
-		ds.Device.Account = &waAdv.ADVSignedDeviceIdentity{
-			Details: make([]byte, 0), AccountSignatureKey: make([]byte, 32),
-			AccountSignature: make([]byte, 64), DeviceSignature: make([]byte, 64),
-		}
-	}
+	ds := newEmptyDeviceStore("") // Empty path means no file saving
+	// onDataChanged is nil - no callback
 
+	ds.generateDevice()
+	ds.attachStores()
 	return ds, nil
 }
 
@@ -674,13 +734,33 @@ func (c *Client) RegisterPushNotifications(ctx context.Context, opts *RegisterPu
 
 // Helper to convert thread
 func convertThread(t *table.LSDeleteThenInsertThread) *Thread {
-	return &Thread{
-		ID:                      t.ThreadKey,
-		Type:                    int(t.ThreadType),
-		Name:                    t.ThreadName,
-		LastActivityTimestampMs: t.LastActivityTimestampMs,
-		Snippet:                 t.Snippet,
+	thread := &Thread{
+		ID:                           t.ThreadKey,
+		Type:                         int(t.ThreadType),
+		Name:                         t.ThreadName,
+		LastActivityTimestampMs:      t.LastActivityTimestampMs,
+		Snippet:                      t.Snippet,
+		IsGroup:                      !t.ThreadType.IsOneToOne(),
+		Folder:                       t.FolderName,
+		ParentThreadKey:              t.ParentThreadKey,
+		PictureURL:                   t.ThreadPictureUrl,
+		MutedUntilMs:                 t.MuteExpireTimeMs,
+		LastReadWatermarkTimestampMs: t.LastReadWatermarkTimestampMs,
+		IsUnread:                     t.LastActivityTimestampMs > t.LastReadWatermarkTimestampMs,
+		NeedsAdminApproval:           t.NeedsAdminApprovalForNewParticipant,
+		CustomEmoji:                  t.CustomEmoji,
+		ThemeFbid:                    t.ThemeFbid,
+		MemberCount:                  t.MemberCount,
+		Participants:                 []*ThreadParticipant{},
+	}
+	if t.ThreadType.IsWhatsApp() {
+		server := waTypes.MessengerServer
+		if thread.IsGroup {
+			server = waTypes.GroupServer
+		}
+		thread.E2EEChatJID = waTypes.NewJID(strconv.FormatInt(t.ThreadKey, 10), server).String()
 	}
+	return thread
 }
 
 // Helper to convert message from LSUpsertMessage
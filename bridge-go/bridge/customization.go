@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ThreadThemes maps names to some of the theme FBIDs Messenger accepts. Newer
+// themes can be set by their FBID.
+var ThreadThemes = map[string]int64{
+	"default":  196241301102133,
+	"hotPink":  169463077092846,
+	"aquaBlue": 2442142322678320,
+	"purple":   234137870477637,
+	"coral":    980963458735625,
+	"orange":   175615189761153,
+	"green":    2136751179887052,
+	"lavender": 2058653964378557,
+	"red":      2129984390566328,
+	"yellow":   174636906462322,
+	"tealBlue": 1928399724138152,
+	"aqua":     417639218648241,
+	"mango":    930060997172551,
+	"berry":    164535220883264,
+	"citrus":   370940413392601,
+	"candy":    205488546921017,
+}
+
+const maxNicknameLength = 100
+
+// resolveTheme accepts a theme name from ThreadThemes or any theme FBID
+func resolveTheme(theme string) (int64, error) {
+	if id, ok := ThreadThemes[theme]; ok {
+		return id, nil
+	}
+	if id, err := strconv.ParseInt(theme, 10, 64); err == nil && id > 0 {
+		return id, nil
+	}
+	return 0, fmt.Errorf("unknown theme: %s", theme)
+}
+
+// isSingleEmoji reports whether s is one emoji: a keycap, a flag, or emoji
+// joined by ZWJs, each optionally followed by a variation selector, a skin
+// tone and tag characters
+func isSingleEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return false
+	}
+	if len(runes) == 2 && isRegionalIndicator(runes[0]) && isRegionalIndicator(runes[1]) {
+		return true
+	}
+	if isKeycapBase(runes[0]) {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == 0xFE0F {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == 0x20E3
+	}
+	for i := 0; ; i++ {
+		if i >= len(runes) || !isEmojiRune(runes[i]) {
+			return false
+		}
+		i++
+		if i < len(runes) && (runes[i] == 0xFE0F || runes[i] == 0xFE0E) {
+			i++
+		}
+		if i < len(runes) && runes[i] >= 0x1F3FB && runes[i] <= 0x1F3FF {
+			i++
+		}
+		for i < len(runes) && runes[i] >= 0xE0020 && runes[i] <= 0xE007F {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != 0x200D {
+			return false
+		}
+	}
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isKeycapBase(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '#' || r == '*'
+}
+
+// isEmojiRune reports whether r is in one of the blocks emoji are encoded in
+func isEmojiRune(r rune) bool {
+	switch {
+	case isRegionalIndicator(r):
+		return false
+	case r >= 0x1F000 && r <= 0x1FAFF,
+		r >= 0x2600 && r <= 0x27BF,
+		r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF,
+		r >= 0x2190 && r <= 0x21FF,
+		r >= 0x25A0 && r <= 0x25FF,
+		r >= 0x2900 && r <= 0x297F:
+		return true
+	}
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x24C2, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+	return false
+}
+
+// SetNicknameOptions for setting a participant's nickname
+type SetNicknameOptions struct {
+	ThreadID int64  `json:"threadId"`
+	UserID   int64  `json:"userId"`
+	Nickname string `json:"nickname"` // Empty to clear
+}
+
+// SetNickname sets or clears a participant's nickname in a thread
+func (c *Client) SetNickname(opts *SetNicknameOptions) (*GroupUpdateResult, error) {
+	if utf8.RuneCountInString(opts.Nickname) > maxNicknameLength {
+		return nil, fmt.Errorf("nickname is longer than %d characters", maxNicknameLength)
+	}
+	task := &setNicknameTask{
+		ThreadKey: opts.ThreadID,
+		ContactID: opts.UserID,
+		Nickname:  opts.Nickname,
+		SyncGroup: 1,
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
+
+// SetThreadThemeOptions for changing a thread's theme
+type SetThreadThemeOptions struct {
+	ThreadID int64  `json:"threadId"`
+	Theme    string `json:"theme"` // Name from ThreadThemes or any theme FBID
+}
+
+// SetThreadTheme changes a thread's color theme
+func (c *Client) SetThreadTheme(opts *SetThreadThemeOptions) (*GroupUpdateResult, error) {
+	themeID, err := resolveTheme(opts.Theme)
+	if err != nil {
+		return nil, err
+	}
+	task := &setThemeTask{
+		ThreadKey: opts.ThreadID,
+		ThemeFBID: themeID,
+		SyncGroup: 1,
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
+
+// SetThreadEmojiOptions for changing a thread's quick-reaction emoji
+type SetThreadEmojiOptions struct {
+	ThreadID int64  `json:"threadId"`
+	Emoji    string `json:"emoji"`
+}
+
+// SetThreadEmoji changes the emoji sent by a thread's quick-reaction button
+func (c *Client) SetThreadEmoji(opts *SetThreadEmojiOptions) (*GroupUpdateResult, error) {
+	if opts.Emoji == "" {
+		return nil, fmt.Errorf("emoji is required")
+	}
+	if !isSingleEmoji(opts.Emoji) {
+		return nil, fmt.Errorf("emoji must be a single emoji")
+	}
+	task := &setQuickReactionTask{
+		ThreadKey:   opts.ThreadID,
+		CustomEmoji: opts.Emoji,
+		SyncGroup:   1,
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
@@ -0,0 +1,59 @@
+package bridge
+
+import "testing"
+
+func TestIsSingleEmoji(t *testing.T) {
+	tests := []struct {
+		name  string
+		emoji string
+		want  bool
+	}{
+		{"simple", "👍", true},
+		{"text presentation", "❤", true},
+		{"emoji presentation", "❤️", true},
+		{"skin tone", "👍🏽", true},
+		{"zwj family", "👨‍👩‍👧‍👦", true},
+		{"kiss with skin tones", "🧑🏻‍❤️‍💋‍🧑🏼", true},
+		{"flag", "🇻🇳", true},
+		{"tag flag", "🏴󠁧󠁢󠁳󠁣󠁴󠁿", true},
+		{"keycap", "1️⃣", true},
+		{"keycap without selector", "#⃣", true},
+		{"empty", "", false},
+		{"letters", "abcdefgh", false},
+		{"single letter", "a", false},
+		{"digit", "1", false},
+		{"two emoji", "👍👍", false},
+		{"emoji and text", "👍a", false},
+		{"trailing zwj", "👍‍", false},
+		{"lone regional indicator", "🇻", false},
+		{"three regional indicators", "🇻🇳🇺", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSingleEmoji(tt.emoji); got != tt.want {
+				t.Fatalf("isSingleEmoji(%q) = %v, want %v", tt.emoji, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTheme(t *testing.T) {
+	tests := []struct {
+		theme   string
+		want    int64
+		wantErr bool
+	}{
+		{"hotPink", 169463077092846, false},
+		{"169463077092846", 169463077092846, false},
+		{"1234567890123456", 1234567890123456, false},
+		{"neon", 0, true},
+		{"0", 0, true},
+		{"-5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := resolveTheme(tt.theme)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveTheme(%q) = %d, %v, want %d (error %v)", tt.theme, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
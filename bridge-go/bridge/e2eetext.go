@@ -0,0 +1,108 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/proto/waConsumerApplication"
+	waTypes "go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// everyoneMention is the text an @everyone group mention covers
+const everyoneMention = "@everyone"
+
+// urlRegex finds the first link in a message for link previews
+var urlRegex = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// LinkPreview is the preview of a link in an E2EE text message
+type LinkPreview struct {
+	URL             string `json:"url,omitempty"` // Defaults to the first link in the text
+	Title           string `json:"title,omitempty"`
+	Description     string `json:"description,omitempty"`
+	Thumbnail       []byte `json:"thumbnail,omitempty"` // JPEG
+	ThumbnailWidth  int    `json:"thumbnailWidth,omitempty"`
+	ThumbnailHeight int    `json:"thumbnailHeight,omitempty"`
+}
+
+// buildE2EEMessageText builds the text of an E2EE message with its user mentions
+// and @everyone command. Offsets are the UTF-16 ones from the mention arrays.
+func buildE2EEMessageText(chatJID waTypes.JID, opts *SendMessageOptions) (*waCommon.MessageText, error) {
+	text := opts.Text
+	msgText := &waCommon.MessageText{Text: &text}
+
+	for i, userID := range opts.MentionIDs {
+		jid := waTypes.NewJID(strconv.FormatInt(userID, 10), waTypes.MessengerServer).String()
+		msgText.MentionedJID = append(msgText.MentionedJID, jid)
+		msgText.Mentions = append(msgText.Mentions, &waCommon.Mention{
+			MentionType:  waCommon.Mention_PROFILE.Enum(),
+			MentionedJID: proto.String(jid),
+			Offset:       proto.Uint32(uint32(opts.MentionOffsets[i])),
+			Length:       proto.Uint32(uint32(opts.MentionLengths[i])),
+		})
+	}
+
+	if opts.MentionEveryone {
+		if chatJID.Server != waTypes.GroupServer {
+			return nil, fmt.Errorf("%s mentions are only supported in group chats", everyoneMention)
+		}
+		idx := strings.Index(text, everyoneMention)
+		if idx < 0 {
+			return nil, fmt.Errorf("text must contain %s to mention everyone", everyoneMention)
+		}
+		msgText.Commands = append(msgText.Commands, &waCommon.Command{
+			CommandType: waCommon.Command_EVERYONE.Enum(),
+			Offset:      proto.Uint32(uint32(utf16Len(text[:idx]))),
+			Length:      proto.Uint32(uint32(utf16Len(everyoneMention))),
+		})
+	}
+	return msgText, nil
+}
+
+// buildLinkPreview wraps E2EE message text in an extended text message with a
+// link preview, uploading the thumbnail if there is one. The returned media
+// handle must be passed with the send.
+func (c *Client) buildLinkPreview(msgText *waCommon.MessageText, preview *LinkPreview) (*waConsumerApplication.ConsumerApplication_ExtendedTextMessage, string, error) {
+	text := msgText.GetText()
+	matched := preview.URL
+	if matched == "" {
+		matched = urlRegex.FindString(text)
+		if matched == "" {
+			return nil, "", fmt.Errorf("link preview needs a url or a link in the text")
+		}
+	} else if !strings.Contains(text, matched) {
+		return nil, "", fmt.Errorf("link preview url %s isn't in the text", matched)
+	}
+
+	ext := &waConsumerApplication.ConsumerApplication_ExtendedTextMessage{
+		Text:         msgText,
+		MatchedText:  proto.String(matched),
+		CanonicalURL: proto.String(matched),
+	}
+	if preview.Title != "" {
+		ext.Title = proto.String(preview.Title)
+	}
+	if preview.Description != "" {
+		ext.Description = proto.String(preview.Description)
+	}
+	if len(preview.Thumbnail) == 0 {
+		return ext, "", nil
+	}
+
+	uploaded, err := c.E2EE.Upload(c.ctx, preview.Thumbnail, whatsmeow.MediaLinkThumbnail)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to upload link thumbnail: %w", err)
+	}
+	width, height := preview.ThumbnailWidth, preview.ThumbnailHeight
+	if width == 0 || height == 0 {
+		width, height = 100, 100
+	}
+	if err := ext.SetThumbnail(e2eeImageTransport(uploaded, "image/jpeg", len(preview.Thumbnail), width, height)); err != nil {
+		return nil, "", err
+	}
+	return ext, uploaded.Handle, nil
+}
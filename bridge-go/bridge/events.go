@@ -13,6 +13,7 @@ import (
 	"go.mau.fi/whatsmeow/proto/waArmadilloXMA"
 	"go.mau.fi/whatsmeow/proto/waCommon"
 	"go.mau.fi/whatsmeow/proto/waConsumerApplication"
+	waTypes "go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 
 	"go.mau.fi/mautrix-meta/pkg/messagix"
@@ -51,6 +52,16 @@ const (
 	EventTypeE2EEReaction  EventType = "e2eeReaction"
 	EventTypeE2EEReceipt   EventType = "e2eeReceipt"
 	EventDeviceDataChanged EventType = "deviceDataChanged"
+
+	EventTypeE2EEDecryptFailed      EventType = "e2eeDecryptFailed"
+	EventTypeIdentityChanged        EventType = "identityChanged"
+	EventTypePollUpdate             EventType = "pollUpdate"
+	EventTypeThreadUpdate           EventType = "threadUpdate"
+	EventTypeMessageStatus          EventType = "messageStatus"
+	EventTypeOutboxUpdate           EventType = "outboxUpdate"
+	EventTypeRateLimited            EventType = "rateLimited"
+	EventTypeScheduledMessageSent   EventType = "scheduledMessageSent"
+	EventTypeScheduledMessageFailed EventType = "scheduledMessageFailed"
 )
 
 // Event represents a generic event
@@ -75,16 +86,40 @@ type InitialData struct {
 
 // Thread represents a conversation thread
 type Thread struct {
-	ID                      int64  `json:"id"`
-	Type                    int    `json:"type"`
-	Name                    string `json:"name"`
-	LastActivityTimestampMs int64  `json:"lastActivityTimestampMs"`
-	Snippet                 string `json:"snippet"`
+	ID                           int64                `json:"id"`
+	Type                         int                  `json:"type"`
+	Name                         string               `json:"name"`
+	LastActivityTimestampMs      int64                `json:"lastActivityTimestampMs"`
+	Snippet                      string               `json:"snippet"`
+	IsGroup                      bool                 `json:"isGroup"`
+	Folder                       string               `json:"folder,omitempty"` // "inbox", "pending", "archived", "spam", ...
+	ParentThreadKey              int64                `json:"parentThreadKey,omitempty"`
+	PictureURL                   string               `json:"pictureUrl,omitempty"`
+	E2EEChatJID                  string               `json:"e2eeChatJid,omitempty"`  // Set for threads encrypted over WhatsApp
+	MutedUntilMs                 int64                `json:"mutedUntilMs,omitempty"` // -1 = muted indefinitely
+	LastReadWatermarkTimestampMs int64                `json:"lastReadWatermarkTimestampMs"`
+	IsUnread                     bool                 `json:"isUnread"`
+	UnreadCount                  int                  `json:"unreadCount"` // Counted from messages known to the bridge
+	NeedsAdminApproval           bool                 `json:"needsAdminApproval,omitempty"`
+	CustomEmoji                  string               `json:"customEmoji,omitempty"`
+	ThemeFbid                    int64                `json:"themeFbid,omitempty"`
+	MemberCount                  int64                `json:"memberCount,omitempty"`
+	Participants                 []*ThreadParticipant `json:"participants"`
+}
+
+// ThreadParticipant represents a member of a thread
+type ThreadParticipant struct {
+	UserID                        int64  `json:"userId"`
+	Nickname                      string `json:"nickname,omitempty"`
+	IsAdmin                       bool   `json:"isAdmin,omitempty"`
+	IsSuperAdmin                  bool   `json:"isSuperAdmin,omitempty"`
+	ReadWatermarkTimestampMs      int64  `json:"readWatermarkTimestampMs,omitempty"`
+	DeliveredWatermarkTimestampMs int64  `json:"deliveredWatermarkTimestampMs,omitempty"`
 }
 
 // Attachment represents a media attachment
 type Attachment struct {
-	Type        string  `json:"type"` // "image", "video", "audio", "file", "sticker", "gif", "voice", "location", "link"
+	Type        string  `json:"type"` // "image", "video", "audio", "file", "sticker", "gif", "voice", "location", "link", "poll"
 	URL         string  `json:"url,omitempty"`
 	FileName    string  `json:"fileName,omitempty"`
 	MimeType    string  `json:"mimeType,omitempty"`
@@ -103,6 +138,23 @@ type Attachment struct {
 	MediaSHA256    []byte `json:"mediaSha256,omitempty"`
 	MediaEncSHA256 []byte `json:"mediaEncSha256,omitempty"`
 	DirectPath     string `json:"directPath,omitempty"`
+	// For poll attachments
+	Poll *Poll `json:"poll,omitempty"`
+}
+
+// Poll represents a poll as shown on its card in the thread
+type Poll struct {
+	ID       int64         `json:"id"`
+	Question string        `json:"question"`
+	Options  []*PollOption `json:"options"` // Cards only include the first three options
+}
+
+// PollOption represents a poll option and its votes
+type PollOption struct {
+	ID          int64  `json:"id"`
+	Text        string `json:"text"`
+	VoteCount   int64  `json:"voteCount"`
+	VotePercent int64  `json:"votePercent"`
 }
 
 // ReplyTo represents reply info
@@ -135,10 +187,77 @@ type Message struct {
 
 // MessageEditEvent represents a message edit
 type MessageEditEvent struct {
-	MessageID   string `json:"messageId"`
+	MessageID    string        `json:"messageId"`
+	ThreadID     int64         `json:"threadId"` // 0 if the message isn't known to the bridge
+	ChatJID      string        `json:"chatJid,omitempty"`
+	SenderID     int64         `json:"senderId,omitempty"`
+	NewText      string        `json:"newText"`
+	PreviousText *string       `json:"previousText,omitempty"` // nil if the original isn't known
+	Attachments  []*Attachment `json:"attachments,omitempty"`
+	EditCount    int64         `json:"editCount"`
+	IsE2EE       bool          `json:"isE2EE,omitempty"`
+	TimestampMs  int64         `json:"timestampMs"`
+}
+
+// MessageUnsendEvent represents an unsent (deleted for everyone) message
+type MessageUnsendEvent struct {
+	MessageID    string        `json:"messageId"`
+	ThreadID     int64         `json:"threadId"`
+	ChatJID      string        `json:"chatJid,omitempty"`
+	SenderID     int64         `json:"senderId,omitempty"`
+	PreviousText *string       `json:"previousText,omitempty"` // nil if the original isn't known
+	Attachments  []*Attachment `json:"attachments,omitempty"`
+	IsE2EE       bool          `json:"isE2EE,omitempty"`
+	TimestampMs  int64         `json:"timestampMs"`
+}
+
+// newMessageEditEvent builds an edit event, filling in details from the previous message if known
+func newMessageEditEvent(messageID, newText string, editCount int64, previous *StoredMessage) *MessageEditEvent {
+	evt := &MessageEditEvent{
+		MessageID:   messageID,
+		NewText:     newText,
+		EditCount:   editCount,
+		TimestampMs: timeNowMs(),
+	}
+	if previous != nil {
+		evt.ThreadID = previous.ThreadID
+		evt.ChatJID = previous.ChatJID
+		evt.SenderID = previous.SenderID
+		evt.PreviousText = &previous.Text
+		evt.Attachments = previous.Attachments
+		evt.IsE2EE = previous.IsE2EE
+	}
+	return evt
+}
+
+// newMessageUnsendEvent builds an unsend event, filling in details from the previous message if known
+func newMessageUnsendEvent(messageID string, threadID int64, previous *StoredMessage) *MessageUnsendEvent {
+	evt := &MessageUnsendEvent{
+		MessageID:   messageID,
+		ThreadID:    threadID,
+		TimestampMs: timeNowMs(),
+	}
+	if previous != nil {
+		if evt.ThreadID == 0 {
+			evt.ThreadID = previous.ThreadID
+		}
+		evt.ChatJID = previous.ChatJID
+		evt.SenderID = previous.SenderID
+		evt.PreviousText = &previous.Text
+		evt.Attachments = previous.Attachments
+		evt.IsE2EE = previous.IsE2EE
+	}
+	return evt
+}
+
+// PollUpdateEvent represents a new poll or a change to its votes or options
+type PollUpdateEvent struct {
+	PollID      int64  `json:"pollId"`
 	ThreadID    int64  `json:"threadId"`
-	NewText     string `json:"newText"`
-	EditCount   int64  `json:"editCount"`
+	MessageID   string `json:"messageId"` // Message carrying the updated poll card
+	SenderID    int64  `json:"senderId"`  // Sender of that message, usually the voter for vote notices
+	Poll        *Poll  `json:"poll"`
+	Previous    *Poll  `json:"previous,omitempty"` // Unset if the poll wasn't seen before
 	TimestampMs int64  `json:"timestampMs"`
 }
 
@@ -205,6 +324,17 @@ type E2EEMessage struct {
 	Mentions    []*Mention    `json:"mentions,omitempty"`
 }
 
+// E2EEDecryptFailedEvent is emitted when whatsmeow can't decrypt an incoming E2EE message
+type E2EEDecryptFailedEvent struct {
+	MessageID   string `json:"messageId"`
+	ChatJID     string `json:"chatJid"`
+	SenderJID   string `json:"senderJid"`
+	SenderID    int64  `json:"senderId"`
+	Reason      string `json:"reason"` // "decryptFailed", "unavailable" or a specific unavailable type such as "view_once"
+	Hidden      bool   `json:"hidden,omitempty"`
+	TimestampMs int64  `json:"timestampMs"`
+}
+
 // getEventTypeName returns the type name of an event
 func getEventTypeName(evt any) string {
 	if evt == nil {
@@ -231,9 +361,13 @@ func (c *Client) handleEvent(ctx context.Context, evt any) {
 		c.emitEvent(EventTypeReady, map[string]any{
 			"isNewSession": e.IsNewSession,
 		})
+		c.outbox.notify()
+		c.scheduler.notify()
 
 	case *messagix.Event_Reconnected:
 		c.emitEvent(EventTypeReconnected, nil)
+		c.outbox.notify()
+		c.scheduler.notify()
 
 	case *messagix.Event_SocketError:
 		c.emitEvent(EventTypeError, &ErrorEvent{
@@ -258,7 +392,20 @@ func (c *Client) handleTable(tbl *table.LSTable) {
 	// Process wrapped messages (includes attachments info)
 	// upsert = sync/backfill messages (should NOT emit events)
 	// insert = new real-time messages (should emit events)
-	_, insert := tbl.WrapMessages()
+	upsert, insert := tbl.WrapMessages()
+
+	// Update the state mirror before emitting events so handlers can query it
+	insertMsgs := make([]*Message, len(insert))
+	for i, msg := range insert {
+		insertMsgs[i] = c.convertWrappedMessage(msg)
+	}
+	mirrored := append([]*Message(nil), insertMsgs...)
+	for _, upsertMsgs := range upsert {
+		for _, msg := range upsertMsgs.Messages {
+			mirrored = append(mirrored, c.convertWrappedMessage(msg))
+		}
+	}
+	threadUpdates := c.state.applyTable(tbl, c.threadsFromTable(tbl), mirrored)
 
 	// Track handled message IDs to avoid duplicates
 	handledMsgIds := make(map[string]bool)
@@ -268,14 +415,47 @@ func (c *Client) handleTable(tbl *table.LSTable) {
 	// Only insert messages (real-time new messages) should trigger events
 
 	// Handle inserted messages (new real-time messages)
-	for _, msg := range insert {
-		if msg.MessageId != "" {
-			if handledMsgIds[msg.MessageId] {
+	for _, msg := range insertMsgs {
+		if msg.ID != "" {
+			if handledMsgIds[msg.ID] {
 				continue
 			}
-			handledMsgIds[msg.MessageId] = true
+			handledMsgIds[msg.ID] = true
+		}
+		c.emitEvent(EventTypeMessage, msg)
+	}
+	c.autoMarkRead(insertMsgs)
+
+	// Sync and backfill batches resend thread rows as they are on the server, so a
+	// difference there is a change we missed rather than one that just happened
+	if len(upsert) == 0 && len(tbl.LSUpsertSyncGroupThreadsRange) == 0 {
+		c.emitThreadUpdates(threadUpdates, mirrored)
+	}
+
+	// Handle poll changes, both on the original poll message and on vote notices.
+	// Polls in sync/backfill batches only update the mirror, like their messages.
+	live := make(map[*Message]bool, len(insertMsgs))
+	for _, msg := range insertMsgs {
+		live[msg] = true
+	}
+	for _, msg := range mirrored {
+		for _, att := range msg.Attachments {
+			if att.Poll == nil {
+				continue
+			}
+			// A poll the mirror doesn't know yet (e.g. from before a restart) still has a change to report
+			if previous, changed := c.state.applyPoll(att.Poll); changed && live[msg] {
+				c.emitEvent(EventTypePollUpdate, &PollUpdateEvent{
+					PollID:      att.Poll.ID,
+					ThreadID:    msg.ThreadID,
+					MessageID:   msg.ID,
+					SenderID:    msg.SenderID,
+					Poll:        att.Poll,
+					Previous:    previous,
+					TimestampMs: msg.TimestampMs,
+				})
+			}
 		}
-		c.emitEvent(EventTypeMessage, c.convertWrappedMessage(msg))
 	}
 
 	// Handle simple inserted messages (fallback) - skip if already handled
@@ -292,32 +472,43 @@ func (c *Client) handleTable(tbl *table.LSTable) {
 		})
 	}
 
-	// Handle message edits
+	// Handle message edits (the edit row doesn't include the thread, so it's resolved from the mirror)
 	for _, edit := range tbl.LSEditMessage {
-		c.emitEvent(EventTypeMessageEdit, &MessageEditEvent{
-			MessageID:   edit.MessageID,
-			ThreadID:    0, // Edit doesn't include threadID, will be resolved by client
-			NewText:     edit.Text,
-			EditCount:   edit.EditCount,
-			TimestampMs: timeNowMs(),
-		})
+		previous, editCount := c.state.applyEdit(edit.MessageID, edit.Text, edit.EditCount)
+		c.emitEvent(EventTypeMessageEdit, newMessageEditEvent(edit.MessageID, edit.Text, editCount, previous))
 	}
 
 	// Handle message deletes
 	for _, del := range tbl.LSDeleteMessage {
-		c.emitEvent(EventTypeMessageUnsend, map[string]any{
-			"messageId": del.MessageId,
-			"threadId":  del.ThreadKey,
-		})
+		previous := c.state.applyUnsend(del.MessageId)
+		c.emitEvent(EventTypeMessageUnsend, newMessageUnsendEvent(del.MessageId, del.ThreadKey, previous))
 	}
 
 	// Handle DeleteThenInsert for unsend
 	for _, del := range tbl.LSDeleteThenInsertMessage {
 		if del.IsUnsent {
-			c.emitEvent(EventTypeMessageUnsend, map[string]any{
-				"messageId": del.MessageId,
-				"threadId":  del.ThreadKey,
-			})
+			previous := c.state.applyUnsend(del.MessageId)
+			c.emitEvent(EventTypeMessageUnsend, newMessageUnsendEvent(del.MessageId, del.ThreadKey, previous))
+		}
+	}
+
+	// Update the status of our sends
+	timestamps := messageTimestamps(tbl)
+	for _, r := range tbl.LSReplaceOptimsiticMessage {
+		c.emitMessageStatus(c.sends.markSent(r.OfflineThreadingId, r.MessageId, timestamps[r.MessageId]))
+	}
+	c.sends.setServerTimestamps(timestamps)
+	for _, f := range tbl.LSMarkOptimisticMessageFailed {
+		c.emitMessageStatus(c.sends.advance(f.Otid, SendStatusFailed, "", f.Message))
+	}
+	for _, receipt := range tbl.LSUpdateDeliveryReceipt {
+		if receipt.ContactId != c.FBID {
+			c.emitMessageStatus(c.sends.advanceThread(receipt.ThreadKey, receipt.DeliveredWatermarkTimestampMs, SendStatusDelivered)...)
+		}
+	}
+	for _, receipt := range tbl.LSUpdateReadReceipt {
+		if receipt.ContactId != c.FBID {
+			c.emitMessageStatus(c.sends.advanceThread(receipt.ThreadKey, receipt.ReadWatermarkTimestampMs, SendStatusRead)...)
 		}
 	}
 
@@ -560,8 +751,14 @@ func (c *Client) convertWrappedMessage(msg *table.WrappedMessage) *Message {
 			continue
 		}
 
-		// Skip poll metadata (handled separately if needed)
+		// Poll cards (creation and vote updates)
 		if xma.CTA != nil && strings.HasPrefix(xma.CTA.Type_, "xma_poll_") {
+			if poll := convertPollXMA(xma); poll != nil {
+				m.Attachments = append(m.Attachments, &Attachment{
+					Type: "poll",
+					Poll: poll,
+				})
+			}
 			continue
 		}
 
@@ -591,6 +788,38 @@ func (c *Client) convertWrappedMessage(msg *table.WrappedMessage) *Message {
 	return m
 }
 
+// convertPollXMA converts a poll card XMA, whose list items are the poll options
+func convertPollXMA(xma *table.WrappedXMA) *Poll {
+	poll := &Poll{
+		ID:       xma.CTA.TargetId,
+		Question: xma.TitleText,
+		Options:  []*PollOption{},
+	}
+	if poll.ID == 0 {
+		poll.ID = xma.TargetId
+	}
+	if poll.ID == 0 {
+		return nil
+	}
+	if poll.Question == "" {
+		poll.Question = xma.HeaderTitle
+	}
+
+	items := []PollOption{
+		{ID: xma.ListItemId1, Text: xma.ListItemTitleText1, VoteCount: xma.ListItemTotalCount1, VotePercent: xma.ListItemProgressBarFilledPercentage1},
+		{ID: xma.ListItemId2, Text: xma.ListItemTitleText2, VoteCount: xma.ListItemTotalCount2, VotePercent: xma.ListItemProgressBarFilledPercentage2},
+		{ID: xma.ListItemId3, Text: xma.ListItemTitleText3, VoteCount: xma.ListItemTotalCount3, VotePercent: xma.ListItemProgressBarFilledPercentage3},
+	}
+	for i := range items {
+		if items[i].Text == "" {
+			continue
+		}
+		option := items[i]
+		poll.Options = append(poll.Options, &option)
+	}
+	return poll
+}
+
 // convertBlobAttachment converts a blob attachment to our format
 func (c *Client) convertBlobAttachment(blob *table.LSInsertBlobAttachment) *Attachment {
 	att := &Attachment{
@@ -662,6 +891,8 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 	switch e := evt.(type) {
 	case *events.Connected:
 		c.emitEvent(EventTypeE2EEConnected, nil)
+		c.outbox.notify()
+		c.scheduler.notify()
 
 	case *events.Disconnected:
 		c.emitEvent(EventTypeDisconnected, map[string]any{
@@ -669,6 +900,7 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 		})
 
 	case *events.FBMessage:
+		c.registerE2EEChat(e.Info.Chat)
 		var senderID int64
 		if e.Info.Sender.User != "" {
 			senderID, _ = strconv.ParseInt(e.Info.Sender.User, 10, 64)
@@ -691,13 +923,16 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 		if isE2EEEditMessage(e) {
 			editInfo := extractE2EEEditInfo(e)
 			if editInfo != nil {
-				c.emitEvent(EventTypeMessageEdit, &MessageEditEvent{
-					MessageID:   editInfo.MessageID,
-					ThreadID:    0,
-					NewText:     editInfo.NewText,
-					EditCount:   1,
-					TimestampMs: e.Info.Timestamp.UnixMilli(),
-				})
+				// E2EE edits don't carry a count, so the mirror tracks it
+				previous, editCount := c.state.applyEdit(editInfo.MessageID, editInfo.NewText, 0)
+				evt := newMessageEditEvent(editInfo.MessageID, editInfo.NewText, editCount, previous)
+				evt.ChatJID = e.Info.Chat.String()
+				evt.IsE2EE = true
+				if evt.ThreadID == 0 {
+					evt.ThreadID, _ = strconv.ParseInt(e.Info.Chat.User, 10, 64)
+				}
+				evt.TimestampMs = e.Info.Timestamp.UnixMilli()
+				c.emitEvent(EventTypeMessageEdit, evt)
 			}
 			return
 		}
@@ -706,11 +941,12 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 		if isE2EERevokeMessage(e) {
 			revokedMsgID := extractE2EERevokedMessageID(e)
 			if revokedMsgID != "" {
-				c.emitEvent(EventTypeMessageUnsend, map[string]any{
-					"messageId": revokedMsgID,
-					"threadId":  e.Info.Chat.String(),
-					"isE2EE":    true,
-				})
+				threadID, _ := strconv.ParseInt(e.Info.Chat.User, 10, 64)
+				evt := newMessageUnsendEvent(revokedMsgID, threadID, c.state.applyUnsend(revokedMsgID))
+				evt.ChatJID = e.Info.Chat.String()
+				evt.IsE2EE = true
+				evt.TimestampMs = e.Info.Timestamp.UnixMilli()
+				c.emitEvent(EventTypeMessageUnsend, evt)
 			}
 			return
 		}
@@ -721,9 +957,47 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 			// Message was skipped (e.g., empty live location event)
 			return
 		}
+		c.state.putMessage(storedFromE2EEMessage(msg))
 		c.emitEvent(EventTypeE2EEMessage, msg)
+		c.autoMarkE2EERead(msg)
+
+	case *events.UndecryptableMessage:
+		var senderID int64
+		if e.Info.Sender.User != "" {
+			senderID, _ = strconv.ParseInt(e.Info.Sender.User, 10, 64)
+		}
+		reason := "decryptFailed"
+		if e.IsUnavailable {
+			reason = "unavailable"
+			if e.UnavailableType != events.UnavailableTypeUnknown {
+				reason = string(e.UnavailableType)
+			}
+		}
+		c.Logger.Warn().
+			Str("message_id", e.Info.ID).
+			Stringer("chat", e.Info.Chat).
+			Stringer("sender", e.Info.Sender).
+			Str("reason", reason).
+			Msg("Failed to decrypt E2EE message")
+		c.emitEvent(EventTypeE2EEDecryptFailed, &E2EEDecryptFailedEvent{
+			MessageID:   e.Info.ID,
+			ChatJID:     e.Info.Chat.String(),
+			SenderJID:   e.Info.Sender.String(),
+			SenderID:    senderID,
+			Reason:      reason,
+			Hidden:      e.DecryptFailMode == events.DecryptFailHide,
+			TimestampMs: e.Info.Timestamp.UnixMilli(),
+		})
 
 	case *events.Receipt:
+		if !e.IsFromMe {
+			switch e.Type {
+			case waTypes.ReceiptTypeDelivered:
+				c.emitMessageStatus(c.sends.advanceMessages(e.MessageIDs, SendStatusDelivered)...)
+			case waTypes.ReceiptTypeRead, waTypes.ReceiptTypePlayed:
+				c.emitMessageStatus(c.sends.advanceMessages(e.MessageIDs, SendStatusRead)...)
+			}
+		}
 		c.emitEvent(EventTypeE2EEReceipt, map[string]any{
 			"type":       string(e.Type),
 			"chat":       e.Chat.String(),
@@ -735,6 +1009,8 @@ func (c *Client) handleE2EEEvent(evt interface{}) {
 
 // emitEvent emits an event to the channel
 func (c *Client) emitEvent(eventType EventType, data interface{}) {
+	// Archive before queueing so records are kept even if the channel is full
+	c.archiveEvent(eventType, data)
 	select {
 	case c.eventChan <- &Event{
 		Type:      eventType,
@@ -980,6 +1256,27 @@ func extractE2EEMentions(text *waCommon.MessageText) []*Mention {
 	if text == nil {
 		return nil
 	}
+	// Newer clients send mention positions, in UTF-16 code units like regular mentions
+	if positioned := text.GetMentions(); len(positioned) > 0 {
+		mentions := make([]*Mention, 0, len(positioned))
+		for _, m := range positioned {
+			jid, err := waTypes.ParseJID(m.GetMentionedJID())
+			if err != nil {
+				continue
+			}
+			userID, _ := strconv.ParseInt(jid.User, 10, 64)
+			if userID == 0 {
+				continue
+			}
+			mentions = append(mentions, &Mention{
+				UserID: userID,
+				Offset: int(m.GetOffset()),
+				Length: int(m.GetLength()),
+				Type:   "user",
+			})
+		}
+		return mentions
+	}
 	jids := text.GetMentionedJID()
 	if len(jids) == 0 {
 		return nil
@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/socket"
+	"go.mau.fi/mautrix-meta/pkg/messagix/table"
+	"go.mau.fi/whatsmeow/proto/waMediaTransport"
+)
+
+// ForwardMessageOptions for forwarding messages
+type ForwardMessageOptions struct {
+	ToThreadID     int64  `json:"toThreadId"`
+	ForwardedMsgID string `json:"forwardedMsgId"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// ForwardMessage forwards a regular message to another regular thread.
+// The server copies the original text, attachments and XMA.
+func (c *Client) ForwardMessage(opts *ForwardMessageOptions) (*SendMessageResult, error) {
+	if opts.ForwardedMsgID == "" {
+		return nil, fmt.Errorf("forwardedMsgId is required")
+	}
+	if src := c.state.getMessage(opts.ForwardedMsgID); src != nil && src.IsE2EE {
+		return nil, fmt.Errorf("message %s is end-to-end encrypted, use ForwardE2EEMessage", opts.ForwardedMsgID)
+	}
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		if err := c.Messagix.WaitUntilCanSendMessages(c.ctx, 10*time.Second); err != nil {
+			return nil, err
+		}
+
+		otid := c.newOtid(opts.IdempotencyKey)
+		task := &socket.SendMessageTask{
+			ThreadId:       opts.ToThreadID,
+			Otid:           otid,
+			Source:         table.MESSENGER_INBOX_IN_THREAD,
+			SendType:       table.FORWARD,
+			SyncGroup:      1,
+			ForwardedMsgId: opts.ForwardedMsgID,
+		}
+		return c.executeTrackedSend(task, opts.ToThreadID, otid)
+	})
+}
+
+// ForwardE2EEMessageOptions for forwarding a message into an E2EE chat
+type ForwardE2EEMessageOptions struct {
+	ToChatJID      string `json:"toChatJid"`
+	MessageID      string `json:"messageId"`                // Regular or E2EE message known to the state mirror
+	IdempotencyKey string `json:"idempotencyKey,omitempty"` // Reuse it to retry a partially sent forward
+}
+
+// PartialForwardError is returned when a forward split into several messages
+// fails after some of them were delivered
+type PartialForwardError struct {
+	Sent  []*SendMessageResult // Delivered parts, in order
+	Total int
+	Err   error
+}
+
+func (e *PartialForwardError) Error() string {
+	ids := make([]string, len(e.Sent))
+	for i, result := range e.Sent {
+		ids[i] = result.MessageID
+	}
+	return fmt.Sprintf("forwarded %d of %d parts (%s) before failing: %v", len(e.Sent), e.Total, strings.Join(ids, ", "), e.Err)
+}
+
+func (e *PartialForwardError) Unwrap() error {
+	return e.Err
+}
+
+// ForwardE2EEMessage forwards a recent message into an E2EE chat. E2EE chats
+// can't reference media from other chats, so attachments are downloaded and
+// re-uploaded. Messages with several attachments are sent as several messages,
+// and the result is that of the first one.
+//
+// If a later part fails, a *PartialForwardError lists the parts already sent.
+// Retry with the same IdempotencyKey so they aren't sent again; the parts are
+// only remembered in memory, within the idempotency window.
+func (c *Client) ForwardE2EEMessage(opts *ForwardE2EEMessageOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.forwardE2EEMessage(opts)
+	})
+}
+
+func (c *Client) forwardE2EEMessage(opts *ForwardE2EEMessageOptions) (*SendMessageResult, error) {
+	if c.E2EE == nil || !c.E2EE.IsConnected() {
+		return nil, ErrE2EENotConnected
+	}
+	src := c.state.getMessage(opts.MessageID)
+	if src == nil {
+		return nil, fmt.Errorf("message not found: %s", opts.MessageID)
+	}
+	if src.IsUnsent {
+		return nil, fmt.Errorf("message %s was unsent", opts.MessageID)
+	}
+
+	text := src.Text
+	var media []*Attachment
+	for _, att := range src.Attachments {
+		switch att.Type {
+		case "image", "gif", "video", "audio", "voice", "file", "sticker":
+			media = append(media, att)
+		default:
+			// Links and locations can't be re-uploaded, forward their URL instead
+			if att.URL != "" && !strings.Contains(text, att.URL) {
+				text = strings.TrimSpace(text + "\n" + att.URL)
+			}
+		}
+	}
+
+	if len(media) == 0 {
+		if text == "" {
+			return nil, fmt.Errorf("message %s has no content that can be forwarded", opts.MessageID)
+		}
+		return c.sendE2EEMessage(&SendMessageOptions{
+			E2EEChatJID:    opts.ToChatJID,
+			Text:           text,
+			IdempotencyKey: partKey(opts.IdempotencyKey, 0),
+		})
+	}
+
+	var sent []*SendMessageResult
+	for i, att := range media {
+		caption := ""
+		if i == 0 {
+			caption = text
+		}
+		result, err := c.forwardE2EEAttachment(opts.ToChatJID, att, caption, partKey(opts.IdempotencyKey, i))
+		if err != nil {
+			err = fmt.Errorf("failed to forward %s attachment: %w", att.Type, err)
+			if len(sent) > 0 {
+				return nil, &PartialForwardError{Sent: sent, Total: len(media), Err: err}
+			}
+			return nil, err
+		}
+		sent = append(sent, result)
+	}
+	return sent[0], nil
+}
+
+func (c *Client) forwardE2EEAttachment(chatJID string, att *Attachment, caption, idempotencyKey string) (*SendMessageResult, error) {
+	data, err := c.downloadAttachment(att)
+	if err != nil {
+		return nil, err
+	}
+
+	switch att.Type {
+	case "image", "gif":
+		return c.SendE2EEImage(&SendE2EEImageOptions{
+			ChatJID:        chatJID,
+			Data:           data,
+			IdempotencyKey: idempotencyKey,
+			MimeType:       att.MimeType,
+			Caption:        caption,
+			Width:          att.Width,
+			Height:         att.Height,
+		})
+	case "video":
+		return c.SendE2EEVideo(&SendE2EEVideoOptions{
+			ChatJID:        chatJID,
+			Data:           data,
+			IdempotencyKey: idempotencyKey,
+			MimeType:       att.MimeType,
+			Caption:        caption,
+			Width:          att.Width,
+			Height:         att.Height,
+			Duration:       att.Duration,
+		})
+	case "sticker":
+		return c.SendE2EESticker(&SendE2EEStickerOptions{
+			ChatJID:        chatJID,
+			Data:           data,
+			IdempotencyKey: idempotencyKey,
+			MimeType:       att.MimeType,
+			Width:          att.Width,
+			Height:         att.Height,
+		})
+	case "audio", "voice":
+		return c.SendE2EEAudio(&SendE2EEAudioOptions{
+			ChatJID:        chatJID,
+			Data:           data,
+			IdempotencyKey: idempotencyKey,
+			MimeType:       att.MimeType,
+			Duration:       att.Duration,
+			PTT:            att.Type == "voice",
+		})
+	default:
+		fileName := att.FileName
+		if fileName == "" {
+			fileName = "file"
+		}
+		return c.SendE2EEDocument(&SendE2EEDocumentOptions{
+			ChatJID:        chatJID,
+			Data:           data,
+			IdempotencyKey: idempotencyKey,
+			Filename:       fileName,
+			MimeType:       att.MimeType,
+		})
+	}
+}
+
+// downloadAttachment fetches the media of an E2EE or regular attachment
+func (c *Client) downloadAttachment(att *Attachment) ([]byte, error) {
+	if att.DirectPath != "" && len(att.MediaKey) > 0 {
+		directPath := att.DirectPath
+		integral := &waMediaTransport.WAMediaTransport_Integral{
+			MediaKey:      att.MediaKey,
+			FileSHA256:    att.MediaSHA256,
+			FileEncSHA256: att.MediaEncSHA256,
+			DirectPath:    &directPath,
+		}
+		data, err := c.E2EE.DownloadFB(c.ctx, integral, e2eeMediaType(att.Type))
+		if err != nil {
+			return nil, fmt.Errorf("failed to download E2EE media: %w", err)
+		}
+		return data, nil
+	}
+	if att.URL != "" {
+		return c.DownloadMedia(att.URL)
+	}
+	return nil, fmt.Errorf("attachment has no downloadable media")
+}
+
+// partKey derives the key for one of the messages a forward is split into
+func partKey(idempotencyKey string, part int) string {
+	if idempotencyKey == "" {
+		return ""
+	}
+	return idempotencyKey + "/" + strconv.Itoa(part)
+}
@@ -0,0 +1,203 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/socket"
+	"go.mau.fi/mautrix-meta/pkg/messagix/table"
+)
+
+// GroupUpdateResult is the state of a group after a management operation
+type GroupUpdateResult struct {
+	ThreadID int64   `json:"threadId"`
+	Thread   *Thread `json:"thread,omitempty"` // nil if the thread isn't known to the state mirror yet
+}
+
+// groupResult applies a task response to the state mirror and returns the group's new state
+func (c *Client) groupResult(tbl *table.LSTable, threadID int64) *GroupUpdateResult {
+	if tbl != nil {
+		// Our own changes, which won't produce events once the server echoes them
+		updates := c.state.applyTable(tbl, c.threadsFromTable(tbl), nil)
+		for _, update := range updates {
+			update.ActorID = c.FBID
+		}
+		c.emitThreadUpdates(updates, nil)
+	}
+	return &GroupUpdateResult{
+		ThreadID: threadID,
+		Thread:   c.state.getThread(threadID),
+	}
+}
+
+// CreateGroupOptions for creating a group thread
+type CreateGroupOptions struct {
+	UserIDs []int64 `json:"userIds"` // Participants other than ourselves
+	Name    string  `json:"name,omitempty"`
+}
+
+// CreateGroup creates a group thread with the given participants
+func (c *Client) CreateGroup(opts *CreateGroupOptions) (*GroupUpdateResult, error) {
+	if len(opts.UserIDs) < 2 {
+		return nil, fmt.Errorf("a group needs at least two other participants")
+	}
+
+	otid := c.ids.next()
+	task := &socket.CreateGroupTask{
+		Participants: append([]int64{c.FBID}, opts.UserIDs...),
+		SendPayload: socket.CreateGroupPayload{
+			ThreadID:  otid,
+			Otid:      strconv.FormatInt(otid, 10),
+			Source:    int64(table.MESSENGER_INBOX_IN_THREAD),
+			SendType:  int64(table.TEXT),
+			SyncGroup: 1,
+		},
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	threadID := createdGroupID(tbl, otid)
+	if threadID == 0 {
+		return nil, fmt.Errorf("no group thread in create response")
+	}
+
+	if opts.Name != "" {
+		if err := c.RenameThread(&RenameThreadOptions{ThreadID: threadID, NewName: opts.Name}); err != nil {
+			return nil, fmt.Errorf("group %d created but naming it failed: %w", threadID, err)
+		}
+	}
+	result := c.groupResult(tbl, threadID)
+	if opts.Name != "" && result.Thread != nil {
+		result.Thread.Name = opts.Name
+	}
+	return result, nil
+}
+
+// createdGroupID finds the server key of the group created with an optimistic thread key.
+// The response can carry other threads from the same sync batch, so only rows for that key count.
+func createdGroupID(tbl *table.LSTable, optimisticKey int64) int64 {
+	if tbl == nil {
+		return 0
+	}
+	for _, replace := range tbl.LSReplaceOptimisticThread {
+		if replace.ClientThreadKey == optimisticKey {
+			return replace.ServerThreadKey
+		}
+	}
+	// The server may keep the optimistic key as the thread key
+	for _, t := range tbl.LSDeleteThenInsertThread {
+		if t.ThreadKey == optimisticKey {
+			return optimisticKey
+		}
+	}
+	for _, t := range tbl.LSUpdateOrInsertThread {
+		if t.ThreadKey == optimisticKey {
+			return optimisticKey
+		}
+	}
+	return 0
+}
+
+// AddParticipantsOptions for adding people to a group
+type AddParticipantsOptions struct {
+	ThreadID int64   `json:"threadId"`
+	UserIDs  []int64 `json:"userIds"`
+}
+
+// AddParticipants adds people to a group. In groups with approval mode on,
+// non-admins' additions become requests for an admin to approve.
+func (c *Client) AddParticipants(opts *AddParticipantsOptions) (*GroupUpdateResult, error) {
+	if len(opts.UserIDs) == 0 {
+		return nil, fmt.Errorf("userIds is required")
+	}
+	task := &socket.AddParticipantsTask{
+		ThreadKey:  opts.ThreadID,
+		ContactIDs: opts.UserIDs,
+		SyncGroup:  1,
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
+
+// RemoveParticipantOptions for removing someone from a group
+type RemoveParticipantOptions struct {
+	ThreadID int64 `json:"threadId"`
+	UserID   int64 `json:"userId"`
+}
+
+// RemoveParticipant removes someone from a group
+func (c *Client) RemoveParticipant(opts *RemoveParticipantOptions) (*GroupUpdateResult, error) {
+	task := &socket.RemoveParticipantTask{
+		ThreadID:  opts.ThreadID,
+		ContactID: opts.UserID,
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
+
+// LeaveGroupOptions for leaving a group
+type LeaveGroupOptions struct {
+	ThreadID int64 `json:"threadId"`
+}
+
+// LeaveGroup removes ourselves from a group
+func (c *Client) LeaveGroup(opts *LeaveGroupOptions) (*GroupUpdateResult, error) {
+	return c.RemoveParticipant(&RemoveParticipantOptions{
+		ThreadID: opts.ThreadID,
+		UserID:   c.FBID,
+	})
+}
+
+// SetAdminOptions for promoting or demoting a group admin
+type SetAdminOptions struct {
+	ThreadID int64 `json:"threadId"`
+	UserID   int64 `json:"userId"`
+	IsAdmin  bool  `json:"isAdmin"`
+}
+
+// SetAdmin promotes a participant to admin or demotes them
+func (c *Client) SetAdmin(opts *SetAdminOptions) (*GroupUpdateResult, error) {
+	task := &setAdminTask{
+		ThreadKey: opts.ThreadID,
+		ContactID: opts.UserID,
+		SyncGroup: 1,
+	}
+	if opts.IsAdmin {
+		task.IsAdmin = 1
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
+
+// SetApprovalModeOptions for toggling group approval mode
+type SetApprovalModeOptions struct {
+	ThreadID int64 `json:"threadId"`
+	Enabled  bool  `json:"enabled"`
+}
+
+// SetApprovalMode sets whether admins must approve new participants
+func (c *Client) SetApprovalMode(opts *SetApprovalModeOptions) (*GroupUpdateResult, error) {
+	task := &setApprovalModeTask{
+		ThreadKey: opts.ThreadID,
+		SyncGroup: 1,
+	}
+	if opts.Enabled {
+		task.Value = 1
+	}
+	tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return c.groupResult(tbl, opts.ThreadID), nil
+}
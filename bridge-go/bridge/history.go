@@ -0,0 +1,134 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/socket"
+)
+
+const (
+	defaultFetchMessagesLimit = 20
+	maxFetchMessagesLimit     = 200
+)
+
+// FetchMessagesOptions for fetching message history
+type FetchMessagesOptions struct {
+	ThreadID          int64  `json:"threadId"`
+	BeforeTimestampMs int64  `json:"beforeTimestampMs,omitempty"` // Ignored if Cursor is set
+	Cursor            string `json:"cursor,omitempty"`            // From a previous MessagePage
+	Limit             int    `json:"limit,omitempty"`
+}
+
+// MessagePage is a page of message history, newest messages last
+type MessagePage struct {
+	Messages []*Message `json:"messages"`
+	Cursor   string     `json:"cursor,omitempty"` // Pass to FetchMessages for older messages
+	HasMore  bool       `json:"hasMore"`
+}
+
+// messageCursor points at the oldest message returned so far
+type messageCursor struct {
+	TimestampMs int64
+	MessageID   string
+}
+
+func (mc messageCursor) String() string {
+	return strconv.FormatInt(mc.TimestampMs, 10) + ":" + mc.MessageID
+}
+
+func parseMessageCursor(s string) (messageCursor, error) {
+	ts, id, ok := strings.Cut(s, ":")
+	if !ok {
+		return messageCursor{}, fmt.Errorf("invalid cursor")
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return messageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return messageCursor{TimestampMs: timestamp, MessageID: id}, nil
+}
+
+// FetchMessages fetches messages older than a cursor or timestamp in a thread
+func (c *Client) FetchMessages(opts *FetchMessagesOptions) (*MessagePage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultFetchMessagesLimit
+	} else if limit > maxFetchMessagesLimit {
+		limit = maxFetchMessagesLimit
+	}
+
+	ref := messageCursor{TimestampMs: opts.BeforeTimestampMs}
+	if opts.Cursor != "" {
+		var err error
+		if ref, err = parseMessageCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	} else if ref.TimestampMs == 0 {
+		ref.TimestampMs = timeNowMs()
+	}
+
+	page := &MessagePage{Messages: []*Message{}}
+	seen := make(map[string]bool)
+	hasMore := true
+	for len(page.Messages) < limit && hasMore {
+		task := &socket.FetchMessagesTask{
+			ThreadKey:            opts.ThreadID,
+			Direction:            0, // older
+			ReferenceTimestampMs: ref.TimestampMs,
+			ReferenceMessageId:   ref.MessageID,
+			SyncGroup:            1,
+			Cursor:               c.Messagix.SyncManager.GetCursor(1),
+		}
+		tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		if tbl == nil {
+			break
+		}
+		upserts, _ := tbl.WrapMessages()
+		upsert := upserts[opts.ThreadID]
+		if upsert == nil || len(upsert.Messages) == 0 {
+			break
+		}
+
+		added := 0
+		for _, msg := range upsert.Messages {
+			if seen[msg.MessageId] || msg.MessageId == ref.MessageID {
+				continue
+			}
+			seen[msg.MessageId] = true
+			page.Messages = append(page.Messages, c.convertWrappedMessage(msg))
+			added++
+		}
+		hasMore = upsert.Range != nil && upsert.Range.HasMoreBefore
+		if added == 0 || upsert.Range == nil {
+			break
+		}
+		ref = messageCursor{TimestampMs: upsert.Range.MinTimestampMs, MessageID: upsert.Range.MinMessageId}
+	}
+
+	// Keep the newest messages if the server returned more than requested
+	sort.SliceStable(page.Messages, func(i, j int) bool {
+		return page.Messages[i].TimestampMs > page.Messages[j].TimestampMs
+	})
+	if len(page.Messages) > limit {
+		page.Messages = page.Messages[:limit]
+		hasMore = true
+	}
+	sort.SliceStable(page.Messages, func(i, j int) bool {
+		return page.Messages[i].TimestampMs < page.Messages[j].TimestampMs
+	})
+
+	if len(page.Messages) > 0 {
+		oldest := page.Messages[0]
+		page.HasMore = hasMore
+		if hasMore {
+			page.Cursor = messageCursor{TimestampMs: oldest.TimestampMs, MessageID: oldest.ID}.String()
+		}
+	}
+	return page, nil
+}
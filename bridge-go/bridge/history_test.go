@@ -0,0 +1,33 @@
+package bridge
+
+import "testing"
+
+func TestMessageCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    messageCursor
+		wantErr bool
+	}{
+		{"timestamp and id", "1700000000000:mid.$abc", messageCursor{TimestampMs: 1700000000000, MessageID: "mid.$abc"}, false},
+		{"id with colon", "5:a:b", messageCursor{TimestampMs: 5, MessageID: "a:b"}, false},
+		{"timestamp only", "42:", messageCursor{TimestampMs: 42}, false},
+		{"no separator", "1700000000000", messageCursor{}, true},
+		{"bad timestamp", "abc:mid.$abc", messageCursor{}, true},
+		{"empty", "", messageCursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMessageCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMessageCursor(%q) error = %v, wantErr %v", tt.cursor, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseMessageCursor(%q) = %+v, want %+v", tt.cursor, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.cursor {
+				t.Fatalf("String() = %q, want %q", got.String(), tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultIdempotencyWindow is how long results of sends with an idempotency key are remembered
+const defaultIdempotencyWindow = 24 * time.Hour
+
+// idGenerator hands out unique, increasing IDs based on the current time in nanoseconds
+type idGenerator struct {
+	last atomic.Int64
+}
+
+func (g *idGenerator) next() int64 {
+	for {
+		last := g.last.Load()
+		id := time.Now().UnixNano()
+		if id <= last {
+			id = last + 1
+		}
+		if g.last.CompareAndSwap(last, id) {
+			return id
+		}
+	}
+}
+
+// newOtid returns the offline threading ID for a send. With an idempotency key
+// the ID is derived from the key, so a retried send reuses it.
+func (c *Client) newOtid(idempotencyKey string) int64 {
+	if idempotencyKey == "" {
+		return c.ids.next()
+	}
+	sum := sha256.Sum256([]byte(strconv.FormatInt(c.FBID, 10) + ":" + idempotencyKey))
+	return int64(binary.BigEndian.Uint64(sum[:8]) >> 1)
+}
+
+// newE2EEMessageID returns the message ID for an E2EE send, derived from the idempotency key if set
+func (c *Client) newE2EEMessageID(idempotencyKey string) string {
+	return strconv.FormatInt(c.newOtid(idempotencyKey), 10)
+}
+
+// scopedKey namespaces an idempotency key by operation, so a key reused for a
+// reaction, edit or unsend doesn't return the cached result of another send
+func scopedKey(scope, idempotencyKey string) string {
+	if idempotencyKey == "" {
+		return ""
+	}
+	return scope + ":" + idempotencyKey
+}
+
+// idempotentCall is a send with an idempotency key, in flight or finished
+type idempotentCall struct {
+	done    chan struct{}
+	result  *SendMessageResult
+	err     error
+	expires time.Time
+}
+
+// idempotencyCache remembers the results of sends by idempotency key
+type idempotencyCache struct {
+	mu     sync.Mutex
+	window time.Duration
+	calls  map[string]*idempotentCall
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	if window <= 0 {
+		window = defaultIdempotencyWindow
+	}
+	return &idempotencyCache{
+		window: window,
+		calls:  make(map[string]*idempotentCall),
+	}
+}
+
+// do runs send once per key within the window. Concurrent calls with the same key
+// wait for the first one, and later calls get its result. Failed sends aren't
+// remembered, so they can be retried with the same key.
+func (ic *idempotencyCache) do(key string, send func() (*SendMessageResult, error)) (*SendMessageResult, error) {
+	if key == "" {
+		return send()
+	}
+
+	ic.mu.Lock()
+	now := time.Now()
+	for k, call := range ic.calls {
+		if !call.expires.IsZero() && now.After(call.expires) {
+			delete(ic.calls, k)
+		}
+	}
+	if call, ok := ic.calls[key]; ok {
+		ic.mu.Unlock()
+		<-call.done
+		if call.err == nil {
+			result := *call.result
+			return &result, nil
+		}
+		// The first attempt failed, try again
+		return ic.do(key, send)
+	}
+	call := &idempotentCall{done: make(chan struct{})}
+	ic.calls[key] = call
+	ic.mu.Unlock()
+
+	call.result, call.err = send()
+
+	ic.mu.Lock()
+	if call.err != nil {
+		delete(ic.calls, key)
+	} else {
+		call.expires = time.Now().Add(ic.window)
+	}
+	ic.mu.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	result := *call.result
+	return &result, nil
+}
@@ -0,0 +1,112 @@
+package bridge
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyCacheDo(t *testing.T) {
+	errSend := errors.New("send failed")
+	tests := []struct {
+		name      string
+		keys      []string
+		failFirst bool
+		window    time.Duration
+		sleep     time.Duration // between calls
+		wantSends int
+	}{
+		{"no key sends every time", []string{"", ""}, false, time.Hour, 0, 2},
+		{"same key sends once", []string{"a", "a", "a"}, false, time.Hour, 0, 1},
+		{"different keys", []string{"a", "b"}, false, time.Hour, 0, 2},
+		{"scoped keys", []string{scopedKey("edit", "a"), scopedKey("unsend", "a")}, false, time.Hour, 0, 2},
+		{"failure isn't remembered", []string{"a", "a", "a"}, true, time.Hour, 0, 2},
+		{"expired result", []string{"a", "a"}, false, time.Millisecond, 5 * time.Millisecond, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := newIdempotencyCache(tt.window)
+			sends := 0
+			for i, key := range tt.keys {
+				if i > 0 {
+					time.Sleep(tt.sleep)
+				}
+				result, err := ic.do(key, func() (*SendMessageResult, error) {
+					sends++
+					if tt.failFirst && sends == 1 {
+						return nil, errSend
+					}
+					return &SendMessageResult{MessageID: "mid." + key}, nil
+				})
+				if tt.failFirst && i == 0 {
+					if !errors.Is(err, errSend) {
+						t.Fatalf("call %d: got error %v, want %v", i, err, errSend)
+					}
+					continue
+				}
+				if err != nil || result.MessageID != "mid."+key {
+					t.Fatalf("call %d: got %+v, %v", i, result, err)
+				}
+			}
+			if sends != tt.wantSends {
+				t.Fatalf("sent %d times, want %d", sends, tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestIdempotencyCacheConcurrent(t *testing.T) {
+	ic := newIdempotencyCache(time.Hour)
+	var sends atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]*SendMessageResult, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = ic.do("a", func() (*SendMessageResult, error) {
+				sends.Add(1)
+				<-release
+				return &SendMessageResult{MessageID: "mid.a"}, nil
+			})
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if sends.Load() != 1 {
+		t.Fatalf("sent %d times, want 1", sends.Load())
+	}
+	for i, result := range results {
+		if result == nil || result.MessageID != "mid.a" {
+			t.Fatalf("result %d = %+v", i, result)
+		}
+	}
+	// Each caller gets its own copy
+	results[0].MessageID = "changed"
+	if results[1].MessageID != "mid.a" {
+		t.Fatal("results share memory")
+	}
+}
+
+func TestNewOtid(t *testing.T) {
+	c := &Client{FBID: 1}
+	if c.newOtid("key") != c.newOtid("key") {
+		t.Fatal("otid for the same key differs")
+	}
+	if c.newOtid("key") == c.newOtid("other") {
+		t.Fatal("otid for different keys is equal")
+	}
+	if c.newOtid("key") == (&Client{FBID: 2}).newOtid("key") {
+		t.Fatal("otid doesn't depend on the account")
+	}
+	if c.newOtid("key") < 0 {
+		t.Fatal("otid is negative")
+	}
+	if a, b := c.newOtid(""), c.newOtid(""); a >= b {
+		t.Fatalf("otids without a key aren't increasing: %d, %d", a, b)
+	}
+}
@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IdentityTrustPolicy controls how changed E2EE identity keys are handled
+type IdentityTrustPolicy string
+
+const (
+	// IdentityTrustTOFU trusts the first key seen for an address. A changed key is
+	// reported as untrusted, and whatsmeow drops the old identity and sessions before
+	// trusting the new key on the next attempt.
+	IdentityTrustTOFU IdentityTrustPolicy = "tofu"
+	// IdentityTrustAlways accepts changed keys immediately and only notifies the host.
+	IdentityTrustAlways IdentityTrustPolicy = "always"
+	// IdentityTrustManual blocks changed keys until the host approves them with TrustIdentity.
+	IdentityTrustManual IdentityTrustPolicy = "manual"
+)
+
+// parseIdentityTrustPolicy parses a policy name, defaulting to trust on first use
+func parseIdentityTrustPolicy(s string) (IdentityTrustPolicy, error) {
+	switch IdentityTrustPolicy(s) {
+	case "", IdentityTrustTOFU:
+		return IdentityTrustTOFU, nil
+	case IdentityTrustAlways:
+		return IdentityTrustAlways, nil
+	case IdentityTrustManual:
+		return IdentityTrustManual, nil
+	default:
+		return "", fmt.Errorf("unknown identity trust policy: %s", s)
+	}
+}
+
+// IdentityChangedEvent is emitted when a contact's E2EE identity key changes
+type IdentityChangedEvent struct {
+	Address         string              `json:"address"` // Signal address ("user:device")
+	UserID          int64               `json:"userId,omitempty"`
+	OldFingerprint  string              `json:"oldFingerprint"`
+	NewFingerprint  string              `json:"newFingerprint"`
+	Policy          IdentityTrustPolicy `json:"policy"`
+	Trusted         bool                `json:"trusted"`                   // true if the new key was accepted without approval
+	PendingApproval bool                `json:"pendingApproval,omitempty"` // true if TrustIdentity must be called
+	TimestampMs     int64               `json:"timestampMs"`
+}
+
+// PendingIdentity is a changed identity key waiting for approval
+type PendingIdentity struct {
+	Address        string `json:"address"`
+	UserID         int64  `json:"userId,omitempty"`
+	OldFingerprint string `json:"oldFingerprint"`
+	NewFingerprint string `json:"newFingerprint"`
+}
+
+// identityFingerprint returns a stable hex fingerprint of an identity key
+func identityFingerprint(key [32]byte) string {
+	hash := sha256.Sum256(key[:])
+	return hex.EncodeToString(hash[:])
+}
+
+// addressUserID extracts the user ID from a Signal address ("user[_agent]:device")
+func addressUserID(address string) int64 {
+	user := address
+	if idx := strings.IndexByte(user, ':'); idx >= 0 {
+		user = user[:idx]
+	}
+	if idx := strings.IndexByte(user, '_'); idx >= 0 {
+		user = user[:idx]
+	}
+	id, _ := strconv.ParseInt(user, 10, 64)
+	return id
+}
+
+// PendingIdentities lists changed identity keys waiting for approval
+func (ds *DeviceStore) PendingIdentities() []*PendingIdentity {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	result := make([]*PendingIdentity, 0, len(ds.pendingIdentities))
+	for address, key := range ds.pendingIdentities {
+		pending := &PendingIdentity{
+			Address:        address,
+			UserID:         addressUserID(address),
+			NewFingerprint: identityFingerprint(key),
+		}
+		if existing, ok := ds.identities[address]; ok {
+			pending.OldFingerprint = identityFingerprint(existing)
+		}
+		result = append(result, pending)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
+
+// TrustIdentity approves the pending identity key for an address.
+// The stale session is removed so the next message establishes a new one.
+func (ds *DeviceStore) TrustIdentity(ctx context.Context, address string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	key, ok := ds.pendingIdentities[address]
+	if !ok {
+		return fmt.Errorf("no pending identity for %s", address)
+	}
+	ds.identities[address] = key
+	delete(ds.pendingIdentities, address)
+	delete(ds.sessions, address)
+	go ds.Save()
+	return nil
+}
+
+// TrustIdentity approves a changed E2EE identity key for a Signal address
+func (c *Client) TrustIdentity(address string) error {
+	return c.DeviceStore.TrustIdentity(c.ctx, address)
+}
+
+// GetPendingIdentities returns identity changes awaiting approval
+func (c *Client) GetPendingIdentities() []*PendingIdentity {
+	return c.DeviceStore.PendingIdentities()
+}
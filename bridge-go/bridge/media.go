@@ -64,10 +64,11 @@ func (c *Client) UploadMedia(opts *UploadMediaOptions) (*UploadMediaResult, erro
 
 // SendMediaOptions for sending media
 type SendMediaOptions struct {
-	ThreadID   int64   `json:"threadId"`
-	MediaFbIds []int64 `json:"mediaFbIds"`
-	Caption    string  `json:"caption"`
-	ReplyToID  string  `json:"replyToId,omitempty"`
+	ThreadID       int64   `json:"threadId"`
+	MediaFbIds     []int64 `json:"mediaFbIds"`
+	Caption        string  `json:"caption"`
+	ReplyToID      string  `json:"replyToId,omitempty"`
+	IdempotencyKey string  `json:"idempotencyKey,omitempty"`
 }
 
 // SendMedia sends media that has been uploaded
@@ -77,36 +78,44 @@ func (c *Client) SendMedia(opts *SendMediaOptions) (*SendMessageResult, error) {
 		Text:            opts.Caption,
 		AttachmentFbIds: opts.MediaFbIds,
 		ReplyToID:       opts.ReplyToID,
+		IdempotencyKey:  opts.IdempotencyKey,
 	})
 }
 
 // SendStickerOptions for sending stickers
 type SendStickerOptions struct {
-	ThreadID  int64  `json:"threadId"`
-	StickerID int64  `json:"stickerId"`
-	ReplyToID string `json:"replyToId,omitempty"`
+	ThreadID       int64  `json:"threadId"`
+	StickerID      int64  `json:"stickerId"`
+	ReplyToID      string `json:"replyToId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendSticker sends a sticker
 func (c *Client) SendSticker(opts *SendStickerOptions) (*SendMessageResult, error) {
 	return c.SendMessage(&SendMessageOptions{
-		ThreadID:  opts.ThreadID,
-		StickerID: opts.StickerID,
-		ReplyToID: opts.ReplyToID,
+		ThreadID:       opts.ThreadID,
+		StickerID:      opts.StickerID,
+		ReplyToID:      opts.ReplyToID,
+		IdempotencyKey: opts.IdempotencyKey,
 	})
 }
 
 // SendImageOptions for sending images
 type SendImageOptions struct {
-	ThreadID  int64  `json:"threadId"`
-	Data      []byte `json:"data"`
-	Filename  string `json:"filename"`
-	Caption   string `json:"caption"`
-	ReplyToID string `json:"replyToId,omitempty"`
+	ThreadID       int64  `json:"threadId"`
+	Data           []byte `json:"data"`
+	Filename       string `json:"filename"`
+	Caption        string `json:"caption"`
+	ReplyToID      string `json:"replyToId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendImage sends an image
 func (c *Client) SendImage(opts *SendImageOptions) (*SendMessageResult, error) {
+	if err := c.routeUpload(opts.ThreadID); err != nil {
+		return nil, err
+	}
+
 	mimeType := "image/jpeg"
 	if strings.HasSuffix(strings.ToLower(opts.Filename), ".png") {
 		mimeType = "image/png"
@@ -128,24 +137,29 @@ func (c *Client) SendImage(opts *SendImageOptions) (*SendMessageResult, error) {
 	}
 
 	return c.SendMedia(&SendMediaOptions{
-		ThreadID:   opts.ThreadID,
-		MediaFbIds: []int64{uploadResult.FbID},
-		Caption:    opts.Caption,
-		ReplyToID:  opts.ReplyToID,
+		ThreadID:       opts.ThreadID,
+		MediaFbIds:     []int64{uploadResult.FbID},
+		Caption:        opts.Caption,
+		ReplyToID:      opts.ReplyToID,
+		IdempotencyKey: opts.IdempotencyKey,
 	})
 }
 
 // SendVideoOptions for sending videos
 type SendVideoOptions struct {
-	ThreadID  int64  `json:"threadId"`
-	Data      []byte `json:"data"`
-	Filename  string `json:"filename"`
-	Caption   string `json:"caption"`
-	ReplyToID string `json:"replyToId,omitempty"`
+	ThreadID       int64  `json:"threadId"`
+	Data           []byte `json:"data"`
+	Filename       string `json:"filename"`
+	Caption        string `json:"caption"`
+	ReplyToID      string `json:"replyToId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendVideo sends a video
 func (c *Client) SendVideo(opts *SendVideoOptions) (*SendMessageResult, error) {
+	if err := c.routeUpload(opts.ThreadID); err != nil {
+		return nil, err
+	}
 	uploadResult, err := c.UploadMedia(&UploadMediaOptions{
 		ThreadID: opts.ThreadID,
 		Filename: opts.Filename,
@@ -158,23 +172,28 @@ func (c *Client) SendVideo(opts *SendVideoOptions) (*SendMessageResult, error) {
 	}
 
 	return c.SendMedia(&SendMediaOptions{
-		ThreadID:   opts.ThreadID,
-		MediaFbIds: []int64{uploadResult.FbID},
-		Caption:    opts.Caption,
-		ReplyToID:  opts.ReplyToID,
+		ThreadID:       opts.ThreadID,
+		MediaFbIds:     []int64{uploadResult.FbID},
+		Caption:        opts.Caption,
+		ReplyToID:      opts.ReplyToID,
+		IdempotencyKey: opts.IdempotencyKey,
 	})
 }
 
 // SendVoiceOptions for sending voice messages
 type SendVoiceOptions struct {
-	ThreadID  int64  `json:"threadId"`
-	Data      []byte `json:"data"`
-	Filename  string `json:"filename"`
-	ReplyToID string `json:"replyToId,omitempty"`
+	ThreadID       int64  `json:"threadId"`
+	Data           []byte `json:"data"`
+	Filename       string `json:"filename"`
+	ReplyToID      string `json:"replyToId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendVoice sends a voice message
 func (c *Client) SendVoice(opts *SendVoiceOptions) (*SendMessageResult, error) {
+	if err := c.routeUpload(opts.ThreadID); err != nil {
+		return nil, err
+	}
 	uploadResult, err := c.UploadMedia(&UploadMediaOptions{
 		ThreadID: opts.ThreadID,
 		Filename: opts.Filename,
@@ -187,24 +206,29 @@ func (c *Client) SendVoice(opts *SendVoiceOptions) (*SendMessageResult, error) {
 	}
 
 	return c.SendMedia(&SendMediaOptions{
-		ThreadID:   opts.ThreadID,
-		MediaFbIds: []int64{uploadResult.FbID},
-		ReplyToID:  opts.ReplyToID,
+		ThreadID:       opts.ThreadID,
+		MediaFbIds:     []int64{uploadResult.FbID},
+		ReplyToID:      opts.ReplyToID,
+		IdempotencyKey: opts.IdempotencyKey,
 	})
 }
 
 // SendFileOptions for sending files
 type SendFileOptions struct {
-	ThreadID  int64  `json:"threadId"`
-	Data      []byte `json:"data"`
-	Filename  string `json:"filename"`
-	MimeType  string `json:"mimeType"`
-	Caption   string `json:"caption"`
-	ReplyToID string `json:"replyToId,omitempty"`
+	ThreadID       int64  `json:"threadId"`
+	Data           []byte `json:"data"`
+	Filename       string `json:"filename"`
+	MimeType       string `json:"mimeType"`
+	Caption        string `json:"caption"`
+	ReplyToID      string `json:"replyToId,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendFile sends a file
 func (c *Client) SendFile(opts *SendFileOptions) (*SendMessageResult, error) {
+	if err := c.routeUpload(opts.ThreadID); err != nil {
+		return nil, err
+	}
 	uploadResult, err := c.UploadMedia(&UploadMediaOptions{
 		ThreadID: opts.ThreadID,
 		Filename: opts.Filename,
@@ -217,10 +241,11 @@ func (c *Client) SendFile(opts *SendFileOptions) (*SendMessageResult, error) {
 	}
 
 	return c.SendMedia(&SendMediaOptions{
-		ThreadID:   opts.ThreadID,
-		MediaFbIds: []int64{uploadResult.FbID},
-		Caption:    opts.Caption,
-		ReplyToID:  opts.ReplyToID,
+		ThreadID:       opts.ThreadID,
+		MediaFbIds:     []int64{uploadResult.FbID},
+		Caption:        opts.Caption,
+		ReplyToID:      opts.ReplyToID,
+		IdempotencyKey: opts.IdempotencyKey,
 	})
 }
 
@@ -231,23 +256,12 @@ func (c *Client) DownloadMedia(url string) ([]byte, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download media: HTTP %d", resp.StatusCode)
+	}
 	return io.ReadAll(resp.Body)
 }
 
-// ForwardMessageOptions for forwarding messages
-type ForwardMessageOptions struct {
-	ToThreadID     int64  `json:"toThreadId"`
-	ForwardedMsgID string `json:"forwardedMsgId"`
-}
-
-// ForwardMessage forwards a message to another thread
-func (c *Client) ForwardMessage(opts *ForwardMessageOptions) (*SendMessageResult, error) {
-	return c.SendMessage(&SendMessageOptions{
-		ThreadID: opts.ToThreadID,
-		Text:     "", // Will use ForwardedMsgId
-	})
-}
-
 // CreatePollOptions for creating polls
 type CreatePollOptions struct {
 	ThreadID int64    `json:"threadId"`
@@ -257,6 +271,12 @@ type CreatePollOptions struct {
 
 // CreatePoll creates a poll in a thread
 func (c *Client) CreatePoll(opts *CreatePollOptions) error {
+	if opts.Question == "" {
+		return fmt.Errorf("question is required")
+	}
+	if len(opts.Options) == 0 {
+		return fmt.Errorf("at least one option is required")
+	}
 	task := &socket.CreatePollTask{
 		ThreadKey:    opts.ThreadID,
 		QuestionText: opts.Question,
@@ -267,21 +287,29 @@ func (c *Client) CreatePoll(opts *CreatePollOptions) error {
 	return err
 }
 
-// UpdatePollOptions for updating polls
+// UpdatePollOptions for voting on polls and adding options
 type UpdatePollOptions struct {
-	ThreadID        int64   `json:"threadId"`
-	PollID          int64   `json:"pollId"`
-	SelectedOptions []int64 `json:"selectedOptions"`
+	ThreadID        int64    `json:"threadId"`
+	PollID          int64    `json:"pollId"`
+	SelectedOptions []int64  `json:"selectedOptions"`        // Option IDs to vote for, replacing our previous votes
+	AddedOptions    []string `json:"addedOptions,omitempty"` // New options to add to the poll
 }
 
-// UpdatePoll votes on a poll
+// UpdatePoll votes on a poll and/or adds options to it
 func (c *Client) UpdatePoll(opts *UpdatePollOptions) error {
+	if opts.PollID == 0 {
+		return fmt.Errorf("pollId is required")
+	}
 	task := &socket.UpdatePollTask{
 		ThreadKey:       opts.ThreadID,
 		PollID:          opts.PollID,
 		SelectedOptions: opts.SelectedOptions,
+		AddedOptions:    opts.AddedOptions,
 		SyncGroup:       1,
 	}
+	if task.SelectedOptions == nil {
+		task.SelectedOptions = []int64{}
+	}
 	_, err := c.Messagix.ExecuteTasks(c.ctx, task)
 	return err
 }
@@ -508,10 +536,17 @@ type SendE2EEImageOptions struct {
 	Height           int    `json:"height,omitempty"`
 	ReplyToID        string `json:"replyToId,omitempty"`
 	ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+	IdempotencyKey   string `json:"idempotencyKey,omitempty"`
 }
 
 // SendE2EEImage sends an E2EE image
 func (c *Client) SendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.sendE2EEImage(opts)
+	})
+}
+
+func (c *Client) sendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult, error) {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return nil, ErrE2EENotConnected
 	}
@@ -542,26 +577,6 @@ func (c *Client) SendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult,
 		return nil, err
 	}
 
-	// Build media transport (this is the proper way to send media in E2EE)
-	mediaTransport := &waMediaTransport.WAMediaTransport{
-		Integral: &waMediaTransport.WAMediaTransport_Integral{
-			FileSHA256:        uploaded.FileSHA256,
-			MediaKey:          uploaded.MediaKey,
-			FileEncSHA256:     uploaded.FileEncSHA256,
-			DirectPath:        &uploaded.DirectPath,
-			MediaKeyTimestamp: proto.Int64(time.Now().Unix()),
-		},
-		Ancillary: &waMediaTransport.WAMediaTransport_Ancillary{
-			FileLength: proto.Uint64(uint64(len(opts.Data))),
-			Mimetype:   &mimeType,
-			Thumbnail: &waMediaTransport.WAMediaTransport_Ancillary_Thumbnail{
-				ThumbnailWidth:  proto.Uint32(uint32(width)),
-				ThumbnailHeight: proto.Uint32(uint32(height)),
-			},
-			ObjectID: &uploaded.ObjectID,
-		},
-	}
-
 	// Build image message with transport
 	imageMsg := &waConsumerApplication.ConsumerApplication_ImageMessage{}
 	if opts.Caption != "" {
@@ -569,15 +584,7 @@ func (c *Client) SendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult,
 	}
 
 	// Set the transport using the proper method
-	err = imageMsg.Set(&waMediaTransport.ImageTransport{
-		Integral: &waMediaTransport.ImageTransport_Integral{
-			Transport: mediaTransport,
-		},
-		Ancillary: &waMediaTransport.ImageTransport_Ancillary{
-			Height: proto.Uint32(uint32(height)),
-			Width:  proto.Uint32(uint32(width)),
-		},
-	})
+	err = imageMsg.Set(e2eeImageTransport(uploaded, mimeType, len(opts.Data), width, height))
 	if err != nil {
 		return nil, err
 	}
@@ -607,8 +614,8 @@ func (c *Client) SendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult,
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
 		ID:          msgID,
 		MediaHandle: uploaded.Handle,
 	})
@@ -616,12 +623,50 @@ func (c *Client) SendE2EEImage(opts *SendE2EEImageOptions) (*SendMessageResult,
 		return nil, err
 	}
 
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, opts.Caption, uploadedAttachment("image", uploaded, mimeType, len(opts.Data), &Attachment{
+		Width:  width,
+		Height: height,
+	}))
+
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
 
+// e2eeImageTransport builds the transport of an uploaded E2EE image
+func e2eeImageTransport(uploaded whatsmeow.UploadResponse, mimeType string, size, width, height int) *waMediaTransport.ImageTransport {
+	// Build media transport (this is the proper way to send media in E2EE)
+	mediaTransport := &waMediaTransport.WAMediaTransport{
+		Integral: &waMediaTransport.WAMediaTransport_Integral{
+			FileSHA256:        uploaded.FileSHA256,
+			MediaKey:          uploaded.MediaKey,
+			FileEncSHA256:     uploaded.FileEncSHA256,
+			DirectPath:        &uploaded.DirectPath,
+			MediaKeyTimestamp: proto.Int64(time.Now().Unix()),
+		},
+		Ancillary: &waMediaTransport.WAMediaTransport_Ancillary{
+			FileLength: proto.Uint64(uint64(size)),
+			Mimetype:   &mimeType,
+			Thumbnail: &waMediaTransport.WAMediaTransport_Ancillary_Thumbnail{
+				ThumbnailWidth:  proto.Uint32(uint32(width)),
+				ThumbnailHeight: proto.Uint32(uint32(height)),
+			},
+			ObjectID: &uploaded.ObjectID,
+		},
+	}
+	return &waMediaTransport.ImageTransport{
+		Integral: &waMediaTransport.ImageTransport_Integral{
+			Transport: mediaTransport,
+		},
+		Ancillary: &waMediaTransport.ImageTransport_Ancillary{
+			Height: proto.Uint32(uint32(height)),
+			Width:  proto.Uint32(uint32(width)),
+		},
+	}
+}
+
 // SendE2EEVideoOptions for sending E2EE videos
 type SendE2EEVideoOptions struct {
 	ChatJID          string `json:"chatJid"`
@@ -633,10 +678,17 @@ type SendE2EEVideoOptions struct {
 	Duration         int    `json:"duration,omitempty"`
 	ReplyToID        string `json:"replyToId,omitempty"`
 	ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+	IdempotencyKey   string `json:"idempotencyKey,omitempty"`
 }
 
 // SendE2EEVideo sends an E2EE video
 func (c *Client) SendE2EEVideo(opts *SendE2EEVideoOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.sendE2EEVideo(opts)
+	})
+}
+
+func (c *Client) sendE2EEVideo(opts *SendE2EEVideoOptions) (*SendMessageResult, error) {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return nil, ErrE2EENotConnected
 	}
@@ -733,8 +785,8 @@ func (c *Client) SendE2EEVideo(opts *SendE2EEVideoOptions) (*SendMessageResult,
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
 		ID:          msgID,
 		MediaHandle: uploaded.Handle,
 	})
@@ -742,8 +794,15 @@ func (c *Client) SendE2EEVideo(opts *SendE2EEVideoOptions) (*SendMessageResult,
 		return nil, err
 	}
 
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, opts.Caption, uploadedAttachment("video", uploaded, mimeType, len(opts.Data), &Attachment{
+		Width:    width,
+		Height:   height,
+		Duration: opts.Duration,
+	}))
+
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
@@ -757,10 +816,17 @@ type SendE2EEAudioOptions struct {
 	PTT              bool   `json:"ptt"` // Push-to-talk (voice message)
 	ReplyToID        string `json:"replyToId,omitempty"`
 	ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+	IdempotencyKey   string `json:"idempotencyKey,omitempty"`
 }
 
 // SendE2EEAudio sends an E2EE audio/voice message
 func (c *Client) SendE2EEAudio(opts *SendE2EEAudioOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.sendE2EEAudio(opts)
+	})
+}
+
+func (c *Client) sendE2EEAudio(opts *SendE2EEAudioOptions) (*SendMessageResult, error) {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return nil, ErrE2EENotConnected
 	}
@@ -838,8 +904,8 @@ func (c *Client) SendE2EEAudio(opts *SendE2EEAudioOptions) (*SendMessageResult,
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
 		ID:          msgID,
 		MediaHandle: uploaded.Handle,
 	})
@@ -847,8 +913,17 @@ func (c *Client) SendE2EEAudio(opts *SendE2EEAudioOptions) (*SendMessageResult,
 		return nil, err
 	}
 
+	attType := "audio"
+	if opts.PTT {
+		attType = "voice"
+	}
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, "", uploadedAttachment(attType, uploaded, mimeType, len(opts.Data), &Attachment{
+		Duration: opts.Duration,
+	}))
+
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
@@ -861,10 +936,17 @@ type SendE2EEDocumentOptions struct {
 	MimeType         string `json:"mimeType"`
 	ReplyToID        string `json:"replyToId,omitempty"`
 	ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+	IdempotencyKey   string `json:"idempotencyKey,omitempty"`
 }
 
 // SendE2EEDocument sends an E2EE document/file
 func (c *Client) SendE2EEDocument(opts *SendE2EEDocumentOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.sendE2EEDocument(opts)
+	})
+}
+
+func (c *Client) sendE2EEDocument(opts *SendE2EEDocumentOptions) (*SendMessageResult, error) {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return nil, ErrE2EENotConnected
 	}
@@ -940,8 +1022,8 @@ func (c *Client) SendE2EEDocument(opts *SendE2EEDocumentOptions) (*SendMessageRe
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
 		ID:          msgID,
 		MediaHandle: uploaded.Handle,
 	})
@@ -949,8 +1031,13 @@ func (c *Client) SendE2EEDocument(opts *SendE2EEDocumentOptions) (*SendMessageRe
 		return nil, err
 	}
 
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, "", uploadedAttachment("file", uploaded, mimeType, len(opts.Data), &Attachment{
+		FileName: opts.Filename,
+	}))
+
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
@@ -964,10 +1051,17 @@ type SendE2EEStickerOptions struct {
 	Height           int    `json:"height,omitempty"`
 	ReplyToID        string `json:"replyToId,omitempty"`
 	ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+	IdempotencyKey   string `json:"idempotencyKey,omitempty"`
 }
 
 // SendE2EESticker sends an E2EE sticker
 func (c *Client) SendE2EESticker(opts *SendE2EEStickerOptions) (*SendMessageResult, error) {
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		return c.sendE2EESticker(opts)
+	})
+}
+
+func (c *Client) sendE2EESticker(opts *SendE2EEStickerOptions) (*SendMessageResult, error) {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return nil, ErrE2EENotConnected
 	}
@@ -1057,8 +1151,8 @@ func (c *Client) SendE2EESticker(opts *SendE2EEStickerOptions) (*SendMessageResu
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{
 		ID:          msgID,
 		MediaHandle: uploaded.Handle,
 	})
@@ -1066,8 +1160,14 @@ func (c *Client) SendE2EESticker(opts *SendE2EEStickerOptions) (*SendMessageResu
 		return nil, err
 	}
 
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, "", uploadedAttachment("sticker", uploaded, mimeType, len(opts.Data), &Attachment{
+		Width:  width,
+		Height: height,
+	}))
+
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
@@ -1113,23 +1213,6 @@ func (c *Client) DownloadE2EEMedia(opts *DownloadE2EEMediaOptions) (*DownloadE2E
 		}
 	}
 
-	// Map media type string to whatsmeow.MediaType
-	var waMediaType whatsmeow.MediaType
-	switch opts.MediaType {
-	case "image":
-		waMediaType = whatsmeow.MediaImage
-	case "video":
-		waMediaType = whatsmeow.MediaVideo
-	case "audio", "voice":
-		waMediaType = whatsmeow.MediaAudio
-	case "document", "file":
-		waMediaType = whatsmeow.MediaDocument
-	case "sticker":
-		waMediaType = whatsmeow.MediaImage // Stickers use image type
-	default:
-		waMediaType = whatsmeow.MediaDocument
-	}
-
 	// Create WAMediaTransport Integral for download
 	directPath := opts.DirectPath
 	integral := &waMediaTransport.WAMediaTransport_Integral{
@@ -1140,7 +1223,7 @@ func (c *Client) DownloadE2EEMedia(opts *DownloadE2EEMediaOptions) (*DownloadE2E
 	}
 
 	// Download and decrypt
-	data, err := c.E2EE.DownloadFB(c.ctx, integral, waMediaType)
+	data, err := c.E2EE.DownloadFB(c.ctx, integral, e2eeMediaType(opts.MediaType))
 	if err != nil {
 		return nil, fmt.Errorf("failed to download E2EE media: %w", err)
 	}
@@ -1152,6 +1235,24 @@ func (c *Client) DownloadE2EEMedia(opts *DownloadE2EEMediaOptions) (*DownloadE2E
 	}, nil
 }
 
+// e2eeMediaType maps a media or attachment type to a whatsmeow.MediaType
+func e2eeMediaType(mediaType string) whatsmeow.MediaType {
+	switch mediaType {
+	case "image", "gif":
+		return whatsmeow.MediaImage
+	case "video":
+		return whatsmeow.MediaVideo
+	case "audio", "voice":
+		return whatsmeow.MediaAudio
+	case "document", "file":
+		return whatsmeow.MediaDocument
+	case "sticker":
+		return whatsmeow.MediaImage // Stickers use image type
+	default:
+		return whatsmeow.MediaDocument
+	}
+}
+
 // decodeBase64 decodes a base64 string
 func decodeBase64(s string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(s)
@@ -0,0 +1,187 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// mentionMarkupRegex matches inline mention markup: @[Name](id)
+var mentionMarkupRegex = regexp.MustCompile(`@\[([^\]]+)\]\((\d+)\)`)
+
+// NamedMention is a mention located in the text by name
+type NamedMention struct {
+	UserID int64  `json:"userId"`
+	Name   string `json:"name"` // Matched as "@Name" first, then as "Name"
+}
+
+// BuildMentionsOptions for building mention data from text
+type BuildMentionsOptions struct {
+	Text     string          `json:"text"`
+	Markup   bool            `json:"markup,omitempty"` // Replace @[Name](id) markup in Text with "@Name" mentions
+	Mentions []*NamedMention `json:"mentions,omitempty"`
+}
+
+// MentionedText is text with mention data ready for SendMessageOptions.
+// Offsets and lengths are in UTF-16 code units, ordered by offset.
+type MentionedText struct {
+	Text           string  `json:"text"`
+	MentionIDs     []int64 `json:"mentionIds"`
+	MentionOffsets []int   `json:"mentionOffsets"`
+	MentionLengths []int   `json:"mentionLengths"`
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// mentionSpan is a mention by byte range, before conversion to UTF-16
+type mentionSpan struct {
+	userID     int64
+	start, end int
+}
+
+// BuildMentions turns @[Name](id) markup and named mentions into mention data
+// with UTF-16 offsets, as Messenger expects them.
+func BuildMentions(opts *BuildMentionsOptions) (*MentionedText, error) {
+	text := opts.Text
+	var spans []mentionSpan
+
+	if opts.Markup {
+		var b strings.Builder
+		last := 0
+		for _, m := range mentionMarkupRegex.FindAllStringSubmatchIndex(text, -1) {
+			userID, err := strconv.ParseInt(text[m[4]:m[5]], 10, 64)
+			if err != nil || userID == 0 {
+				return nil, fmt.Errorf("invalid mention ID %q", text[m[4]:m[5]])
+			}
+			b.WriteString(text[last:m[0]])
+			start := b.Len()
+			b.WriteString("@" + text[m[2]:m[3]])
+			spans = append(spans, mentionSpan{userID: userID, start: start, end: b.Len()})
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+
+	for _, named := range opts.Mentions {
+		if named.UserID == 0 {
+			return nil, fmt.Errorf("mention %q has no user ID", named.Name)
+		}
+		name := strings.TrimPrefix(named.Name, "@")
+		if name == "" {
+			return nil, fmt.Errorf("mention of %d has no name", named.UserID)
+		}
+		span, ok := findMention(text, "@"+name, spans)
+		if !ok {
+			span, ok = findMention(text, name, spans)
+		}
+		if !ok {
+			return nil, fmt.Errorf("mention %q not found in text", named.Name)
+		}
+		span.userID = named.UserID
+		spans = append(spans, span)
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	result := &MentionedText{
+		Text:           text,
+		MentionIDs:     []int64{},
+		MentionOffsets: []int{},
+		MentionLengths: []int{},
+	}
+	for _, span := range spans {
+		result.MentionIDs = append(result.MentionIDs, span.userID)
+		result.MentionOffsets = append(result.MentionOffsets, utf16Len(text[:span.start]))
+		result.MentionLengths = append(result.MentionLengths, utf16Len(text[span.start:span.end]))
+	}
+	return result, nil
+}
+
+// findMention finds the first occurrence of needle that doesn't overlap a claimed mention
+func findMention(text, needle string, claimed []mentionSpan) (mentionSpan, bool) {
+	for from := 0; from <= len(text); {
+		idx := strings.Index(text[from:], needle)
+		if idx < 0 {
+			break
+		}
+		span := mentionSpan{start: from + idx, end: from + idx + len(needle)}
+		overlaps := false
+		for _, c := range claimed {
+			if span.start < c.end && c.start < span.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			return span, true
+		}
+		from = span.start + 1
+	}
+	return mentionSpan{}, false
+}
+
+// validateMentions checks that parallel mention arrays match and fit the text
+func validateMentions(text string, ids []int64, offsets, lengths []int) error {
+	if len(ids) != len(offsets) || len(ids) != len(lengths) {
+		return fmt.Errorf("mention arrays differ in length: %d IDs, %d offsets, %d lengths", len(ids), len(offsets), len(lengths))
+	}
+	textLen := utf16Len(text)
+	type mentionRange struct{ offset, length int }
+	ranges := make([]mentionRange, len(ids))
+	for i := range ids {
+		if ids[i] == 0 {
+			return fmt.Errorf("mention %d has no user ID", i)
+		}
+		if offsets[i] < 0 || lengths[i] <= 0 || offsets[i]+lengths[i] > textLen {
+			return fmt.Errorf("mention %d (offset %d, length %d) is outside the text (%d UTF-16 units)", i, offsets[i], lengths[i], textLen)
+		}
+		ranges[i] = mentionRange{offsets[i], lengths[i]}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].offset < ranges[j].offset })
+	prevEnd := 0
+	for _, r := range ranges {
+		if r.offset < prevEnd {
+			return fmt.Errorf("mentions overlap at offset %d", r.offset)
+		}
+		prevEnd = r.offset + r.length
+	}
+	return nil
+}
+
+// resolveMentions fills in the mention arrays from markup or named mentions,
+// then validates them
+func (opts *SendMessageOptions) resolveMentions() error {
+	if opts.MentionMarkup || len(opts.Mentions) > 0 {
+		if len(opts.MentionIDs) > 0 {
+			return fmt.Errorf("mentionIds can't be combined with mentionMarkup or mentions")
+		}
+		built, err := BuildMentions(&BuildMentionsOptions{
+			Text:     opts.Text,
+			Markup:   opts.MentionMarkup,
+			Mentions: opts.Mentions,
+		})
+		if err != nil {
+			return err
+		}
+		opts.Text = built.Text
+		opts.MentionIDs = built.MentionIDs
+		opts.MentionOffsets = built.MentionOffsets
+		opts.MentionLengths = built.MentionLengths
+		opts.MentionMarkup = false
+		opts.Mentions = nil
+	}
+	if len(opts.MentionIDs) == 0 && len(opts.MentionOffsets) == 0 && len(opts.MentionLengths) == 0 {
+		return nil
+	}
+	return validateMentions(opts.Text, opts.MentionIDs, opts.MentionOffsets, opts.MentionLengths)
+}
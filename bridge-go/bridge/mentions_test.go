@@ -0,0 +1,142 @@
+package bridge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    BuildMentionsOptions
+		want    MentionedText
+		wantErr string
+	}{
+		{
+			name: "markup",
+			opts: BuildMentionsOptions{Text: "hi @[Anna](1) and @[Bob](2)!", Markup: true},
+			want: MentionedText{Text: "hi @Anna and @Bob!", MentionIDs: []int64{1, 2}, MentionOffsets: []int{3, 13}, MentionLengths: []int{5, 4}},
+		},
+		{
+			name: "offsets in UTF-16 after emoji",
+			opts: BuildMentionsOptions{Text: "👋🏽 @[Anna](1)", Markup: true},
+			want: MentionedText{Text: "👋🏽 @Anna", MentionIDs: []int64{1}, MentionOffsets: []int{5}, MentionLengths: []int{5}},
+		},
+		{
+			name: "name with astral characters",
+			opts: BuildMentionsOptions{Text: "ping @[𝓐nna 🌸](1)", Markup: true},
+			want: MentionedText{Text: "ping @𝓐nna 🌸", MentionIDs: []int64{1}, MentionOffsets: []int{5}, MentionLengths: []int{9}},
+		},
+		{
+			name: "named prefers @name",
+			opts: BuildMentionsOptions{Text: "Anna, ask @Anna", Mentions: []*NamedMention{{UserID: 1, Name: "Anna"}}},
+			want: MentionedText{Text: "Anna, ask @Anna", MentionIDs: []int64{1}, MentionOffsets: []int{10}, MentionLengths: []int{5}},
+		},
+		{
+			name: "named without @",
+			opts: BuildMentionsOptions{Text: "thanks Anna", Mentions: []*NamedMention{{UserID: 1, Name: "@Anna"}}},
+			want: MentionedText{Text: "thanks Anna", MentionIDs: []int64{1}, MentionOffsets: []int{7}, MentionLengths: []int{4}},
+		},
+		{
+			name: "same name twice claims both",
+			opts: BuildMentionsOptions{Text: "@Anna @Anna", Mentions: []*NamedMention{{UserID: 1, Name: "Anna"}, {UserID: 2, Name: "Anna"}}},
+			want: MentionedText{Text: "@Anna @Anna", MentionIDs: []int64{1, 2}, MentionOffsets: []int{0, 6}, MentionLengths: []int{5, 5}},
+		},
+		{
+			name: "markup and named sorted by offset",
+			opts: BuildMentionsOptions{Text: "@Bob and @[Anna](1)", Markup: true, Mentions: []*NamedMention{{UserID: 2, Name: "Bob"}}},
+			want: MentionedText{Text: "@Bob and @Anna", MentionIDs: []int64{2, 1}, MentionOffsets: []int{0, 9}, MentionLengths: []int{4, 5}},
+		},
+		{
+			name: "no mentions",
+			opts: BuildMentionsOptions{Text: "plain @[text]"},
+			want: MentionedText{Text: "plain @[text]", MentionIDs: []int64{}, MentionOffsets: []int{}, MentionLengths: []int{}},
+		},
+		{name: "zero markup ID", opts: BuildMentionsOptions{Text: "@[Anna](0)", Markup: true}, wantErr: "invalid mention ID"},
+		{name: "overflowing markup ID", opts: BuildMentionsOptions{Text: "@[Anna](99999999999999999999)", Markup: true}, wantErr: "invalid mention ID"},
+		{name: "named without ID", opts: BuildMentionsOptions{Text: "@Anna", Mentions: []*NamedMention{{Name: "Anna"}}}, wantErr: "no user ID"},
+		{name: "named without name", opts: BuildMentionsOptions{Text: "@Anna", Mentions: []*NamedMention{{UserID: 1, Name: "@"}}}, wantErr: "no name"},
+		{name: "named not found", opts: BuildMentionsOptions{Text: "hello", Mentions: []*NamedMention{{UserID: 1, Name: "Anna"}}}, wantErr: "not found"},
+		{
+			name:    "named already claimed by markup",
+			opts:    BuildMentionsOptions{Text: "@[Anna](1)", Markup: true, Mentions: []*NamedMention{{UserID: 2, Name: "Anna"}}},
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildMentions(&tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("got %+v, want %+v", *got, tt.want)
+			}
+			if err := validateMentions(got.Text, got.MentionIDs, got.MentionOffsets, got.MentionLengths); err != nil {
+				t.Fatalf("built mentions don't validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		ids     []int64
+		offsets []int
+		lengths []int
+		wantErr string
+	}{
+		{"none", "hi", nil, nil, nil, ""},
+		{"whole text", "@Anna", []int64{1}, []int{0}, []int{5}, ""},
+		{"after emoji", "😀 @Anna", []int64{1}, []int{3}, []int{5}, ""},
+		{"past the end after emoji", "😀 @Anna", []int64{1}, []int{4}, []int{5}, "outside the text"},
+		{"adjacent", "@A@B", []int64{1, 2}, []int{0, 2}, []int{2, 2}, ""},
+		{"unsorted", "@A @B", []int64{2, 1}, []int{3, 0}, []int{2, 2}, ""},
+		{"length mismatch", "@A", []int64{1}, []int{0, 1}, []int{2}, "differ in length"},
+		{"zero ID", "@A", []int64{0}, []int{0}, []int{2}, "no user ID"},
+		{"negative offset", "@A", []int64{1}, []int{-1}, []int{2}, "outside the text"},
+		{"zero length", "@A", []int64{1}, []int{0}, []int{0}, "outside the text"},
+		{"past the end", "@A", []int64{1}, []int{1}, []int{2}, "outside the text"},
+		{"overlap", "@Anna", []int64{1, 2}, []int{0, 2}, []int{3, 3}, "overlap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMentions(tt.text, tt.ids, tt.offsets, tt.lengths)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"é", 1},
+		{"😀", 2},
+		{"👋🏽", 4},
+		{"👨‍👩‍👧", 8},
+	}
+	for _, tt := range tests {
+		if got := utf16Len(tt.s); got != tt.want {
+			t.Errorf("utf16Len(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package bridge
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -17,49 +18,83 @@ import (
 
 // SendMessageOptions for sending messages
 type SendMessageOptions struct {
-	ThreadID        int64   `json:"threadId"`
-	Text            string  `json:"text"`
-	ReplyToID       string  `json:"replyToId,omitempty"`
-	MentionIDs      []int64 `json:"mentionIds,omitempty"`
-	MentionOffsets  []int   `json:"mentionOffsets,omitempty"`
-	MentionLengths  []int   `json:"mentionLengths,omitempty"`
-	AttachmentFbIds []int64 `json:"attachmentFbIds,omitempty"`
-	StickerID       int64   `json:"stickerId,omitempty"`
-	Url             string  `json:"url,omitempty"`
-	IsE2EE          bool    `json:"isE2EE,omitempty"`
-	E2EEChatJID     string  `json:"e2eeChatJid,omitempty"`
+	ThreadID       int64   `json:"threadId"`
+	Text           string  `json:"text"`
+	ReplyToID      string  `json:"replyToId,omitempty"`
+	MentionIDs     []int64 `json:"mentionIds,omitempty"`
+	MentionOffsets []int   `json:"mentionOffsets,omitempty"`
+	MentionLengths []int   `json:"mentionLengths,omitempty"`
+	// MentionMarkup replaces @[Name](id) markup in Text with mentions, instead of the arrays above
+	MentionMarkup bool `json:"mentionMarkup,omitempty"`
+	// Mentions are located in Text by name, instead of the arrays above
+	Mentions        []*NamedMention `json:"mentions,omitempty"`
+	AttachmentFbIds []int64         `json:"attachmentFbIds,omitempty"`
+	StickerID       int64           `json:"stickerId,omitempty"`
+	Url             string          `json:"url,omitempty"`
+	IsE2EE          bool            `json:"isE2EE,omitempty"`
+	E2EEChatJID     string          `json:"e2eeChatJid,omitempty"`
+	// Thread addresses the thread in either form and takes precedence over ThreadID and E2EEChatJID.
+	// Sends to encrypted threads go over E2EE and fail if it isn't connected.
+	Thread *ThreadRef `json:"thread,omitempty"`
 	// E2EE Reply fields
 	E2EEReplyToID        string `json:"e2eeReplyToId,omitempty"`
 	E2EEReplyToSenderJID string `json:"e2eeReplyToSenderJid,omitempty"`
+	// MentionEveryone notifies all members of an E2EE group, the text must contain @everyone
+	MentionEveryone bool `json:"mentionEveryone,omitempty"`
+	// LinkPreview sends E2EE text with a preview of a link in it. Regular sends
+	// use the URL only and let the server build the preview.
+	LinkPreview *LinkPreview `json:"linkPreview,omitempty"`
+	// IdempotencyKey makes retries return the first result instead of sending again
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // SendMessageResult result of sending a message
 type SendMessageResult struct {
 	MessageID   string `json:"messageId"`
+	TrackingID  string `json:"trackingId,omitempty"` // For GetMessageStatus and messageStatus events
 	TimestampMs int64  `json:"timestampMs"`
 }
 
 // SendMessage sends a text message
 func (c *Client) SendMessage(opts *SendMessageOptions) (*SendMessageResult, error) {
-	if opts.IsE2EE && c.E2EE != nil && c.E2EE.IsConnected() {
-		return c.sendE2EEMessage(opts)
+	if err := opts.resolveMentions(); err != nil {
+		return nil, err
+	}
+	if _, err := c.routeMessage(opts); err != nil {
+		return nil, err
 	}
-	return c.sendRegularMessage(opts)
+	return c.idempotency.do(opts.IdempotencyKey, func() (*SendMessageResult, error) {
+		if opts.IsE2EE {
+			return c.sendE2EEMessage(opts)
+		}
+		return c.sendRegularMessage(opts)
+	})
 }
 
 func (c *Client) sendRegularMessage(opts *SendMessageOptions) (*SendMessageResult, error) {
+	if opts.MentionEveryone {
+		return nil, fmt.Errorf("%s mentions are only supported in E2EE group chats", everyoneMention)
+	}
 	if err := c.Messagix.WaitUntilCanSendMessages(c.ctx, 10*time.Second); err != nil {
 		return nil, err
 	}
 
-	otid := time.Now().UnixNano()
+	url := opts.Url
+	if url == "" && opts.LinkPreview != nil {
+		url = opts.LinkPreview.URL
+		if url == "" {
+			url = urlRegex.FindString(opts.Text)
+		}
+	}
+
+	otid := c.newOtid(opts.IdempotencyKey)
 	sendType := table.TEXT
 
 	if opts.StickerID > 0 {
 		sendType = table.STICKER
 	} else if len(opts.AttachmentFbIds) > 0 {
 		sendType = table.MEDIA
-	} else if opts.Url != "" {
+	} else if url != "" {
 		sendType = table.EXTERNAL_MEDIA
 	}
 
@@ -71,7 +106,7 @@ func (c *Client) sendRegularMessage(opts *SendMessageOptions) (*SendMessageResul
 		SendType:        sendType,
 		SyncGroup:       1,
 		StickerId:       opts.StickerID,
-		Url:             opts.Url,
+		Url:             url,
 		AttachmentFBIds: opts.AttachmentFbIds,
 	}
 
@@ -89,28 +124,7 @@ func (c *Client) sendRegularMessage(opts *SendMessageOptions) (*SendMessageResul
 		task.MentionData = buildMentionData(opts.MentionIDs, opts.MentionOffsets, opts.MentionLengths)
 	}
 
-	resp, err := c.Messagix.ExecuteTasks(c.ctx, task)
-	if err != nil {
-		return nil, err
-	}
-
-	result := &SendMessageResult{
-		MessageID:   generateMID(otid),
-		TimestampMs: time.Now().UnixMilli(),
-	}
-
-	// Try to get actual message ID from response
-	otidStr := strconv.FormatInt(otid, 10)
-	if resp != nil {
-		for _, r := range resp.LSReplaceOptimsiticMessage {
-			if r.OfflineThreadingId == otidStr {
-				result.MessageID = r.MessageId
-				break
-			}
-		}
-	}
-
-	return result, nil
+	return c.executeTrackedSend(task, opts.ThreadID, otid)
 }
 
 func (c *Client) sendE2EEMessage(opts *SendMessageOptions) (*SendMessageResult, error) {
@@ -119,13 +133,28 @@ func (c *Client) sendE2EEMessage(opts *SendMessageOptions) (*SendMessageResult,
 		return nil, err
 	}
 
+	msgText, err := buildE2EEMessageText(chatJID, opts)
+	if err != nil {
+		return nil, err
+	}
+	var content waConsumerApplication.ConsumerApplication_Content_Content = &waConsumerApplication.ConsumerApplication_Content_MessageText{
+		MessageText: msgText,
+	}
+	var mediaHandle string
+	if opts.LinkPreview != nil {
+		ext, handle, err := c.buildLinkPreview(msgText, opts.LinkPreview)
+		if err != nil {
+			return nil, err
+		}
+		content = &waConsumerApplication.ConsumerApplication_Content_ExtendedTextMessage{ExtendedTextMessage: ext}
+		mediaHandle = handle
+	}
+
 	waMsg := &waConsumerApplication.ConsumerApplication{
 		Payload: &waConsumerApplication.ConsumerApplication_Payload{
 			Payload: &waConsumerApplication.ConsumerApplication_Payload_Content{
 				Content: &waConsumerApplication.ConsumerApplication_Content{
-					Content: &waConsumerApplication.ConsumerApplication_Content_MessageText{
-						MessageText: &waCommon.MessageText{Text: &opts.Text},
-					},
+					Content: content,
 				},
 			},
 		},
@@ -144,33 +173,45 @@ func (c *Client) sendE2EEMessage(opts *SendMessageOptions) (*SendMessageResult,
 		}
 	}
 
-	msgID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{ID: msgID})
+	msgID := c.newE2EEMessageID(opts.IdempotencyKey)
+	resp, err := c.sendTrackedE2EE(chatJID, waMsg, metadata, whatsmeow.SendRequestExtra{ID: msgID, MediaHandle: mediaHandle})
 	if err != nil {
 		return nil, err
 	}
+	c.recordSentE2EE(chatJID, msgID, resp.Timestamp, opts.Text, nil)
 
 	return &SendMessageResult{
 		MessageID:   msgID,
+		TrackingID:  msgID,
 		TimestampMs: resp.Timestamp.UnixMilli(),
 	}, nil
 }
 
-// SendReaction sends a reaction to a message
-func (c *Client) SendReaction(threadID int64, messageID, emoji string) error {
-	task := &socket.SendReactionTask{
-		ThreadKey:       threadID,
-		MessageID:       messageID,
-		Reaction:        emoji,
-		ActorID:         c.FBID,
-		SendAttribution: table.MESSENGER_INBOX_IN_THREAD,
-	}
-	_, err := c.Messagix.ExecuteTasks(c.ctx, task)
+// SendReaction sends a reaction to a message. With an idempotency key, a retry
+// within the idempotency window doesn't send it again.
+func (c *Client) SendReaction(threadID int64, messageID, emoji, idempotencyKey string) error {
+	_, err := c.idempotency.do(scopedKey("reaction", idempotencyKey), func() (*SendMessageResult, error) {
+		if err := c.rateLimit(RateCategoryReactions, threadID); err != nil {
+			return nil, err
+		}
+		task := &socket.SendReactionTask{
+			ThreadKey:       threadID,
+			MessageID:       messageID,
+			Reaction:        emoji,
+			ActorID:         c.FBID,
+			SendAttribution: table.MESSENGER_INBOX_IN_THREAD,
+		}
+		if _, err := c.Messagix.ExecuteTasks(c.ctx, task); err != nil {
+			return nil, err
+		}
+		return &SendMessageResult{}, nil
+	})
 	return err
 }
 
-// SendE2EEReaction sends an E2EE reaction
-func (c *Client) SendE2EEReaction(chatJIDStr, messageID, senderJIDStr, emoji string) error {
+// SendE2EEReaction sends an E2EE reaction. With an idempotency key, the reaction's
+// message ID is derived from it and a retry doesn't send it again.
+func (c *Client) SendE2EEReaction(chatJIDStr, messageID, senderJIDStr, emoji, idempotencyKey string) error {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return ErrE2EENotConnected
 	}
@@ -183,6 +224,17 @@ func (c *Client) SendE2EEReaction(chatJIDStr, messageID, senderJIDStr, emoji str
 	if err != nil {
 		return err
 	}
+	key := scopedKey("reaction", idempotencyKey)
+	_, err = c.idempotency.do(key, func() (*SendMessageResult, error) {
+		return c.sendE2EEReaction(chatJID, senderJID, messageID, emoji, key)
+	})
+	return err
+}
+
+func (c *Client) sendE2EEReaction(chatJID, senderJID waTypes.JID, messageID, emoji, idempotencyKey string) (*SendMessageResult, error) {
+	if err := c.rateLimitChat(RateCategoryReactions, chatJID); err != nil {
+		return nil, err
+	}
 
 	msgKey := c.E2EE.BuildMessageKey(chatJID, senderJID, messageID)
 	reactionMsg := &waConsumerApplication.ConsumerApplication{
@@ -200,34 +252,73 @@ func (c *Client) SendE2EEReaction(chatJIDStr, messageID, senderJIDStr, emoji str
 		},
 	}
 
-	reactionID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	_, err = c.E2EE.SendFBMessage(c.ctx, chatJID, reactionMsg, nil, whatsmeow.SendRequestExtra{ID: reactionID})
-	return err
+	reactionID := c.newE2EEMessageID(idempotencyKey)
+	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, reactionMsg, nil, whatsmeow.SendRequestExtra{ID: reactionID})
+	if err != nil {
+		return nil, err
+	}
+
+	c.archiveEvent(EventTypeE2EEReaction, map[string]any{
+		"messageId": messageID,
+		"chatJid":   chatJID.String(),
+		"senderId":  c.FBID,
+		"reaction":  emoji,
+	})
+	return &SendMessageResult{MessageID: reactionID, TimestampMs: resp.Timestamp.UnixMilli()}, nil
 }
 
-// EditMessage edits a message
-func (c *Client) EditMessage(messageID, newText string) error {
-	task := &socket.EditMessageTask{
-		MessageID: messageID,
-		Text:      newText,
-	}
-	_, err := c.Messagix.ExecuteTasks(c.ctx, task)
+// EditMessage edits a message. With an idempotency key, a retry within the
+// idempotency window doesn't send the edit again.
+func (c *Client) EditMessage(messageID, newText, idempotencyKey string) error {
+	_, err := c.idempotency.do(scopedKey("edit", idempotencyKey), func() (*SendMessageResult, error) {
+		if err := c.rateLimitMessage(RateCategoryMessages, messageID); err != nil {
+			return nil, err
+		}
+		task := &socket.EditMessageTask{
+			MessageID: messageID,
+			Text:      newText,
+		}
+		if _, err := c.Messagix.ExecuteTasks(c.ctx, task); err != nil {
+			return nil, err
+		}
+		return &SendMessageResult{}, nil
+	})
 	return err
 }
 
-// UnsendMessage unsends/deletes a message
-func (c *Client) UnsendMessage(messageID string) error {
-	task := &socket.DeleteMessageTask{
-		MessageId: messageID,
-	}
-	_, err := c.Messagix.ExecuteTasks(c.ctx, task)
+// UnsendMessage unsends/deletes a message. With an idempotency key, a retry
+// within the idempotency window doesn't send it again.
+func (c *Client) UnsendMessage(messageID, idempotencyKey string) error {
+	_, err := c.idempotency.do(scopedKey("unsend", idempotencyKey), func() (*SendMessageResult, error) {
+		if err := c.rateLimitMessage(RateCategoryMessages, messageID); err != nil {
+			return nil, err
+		}
+		task := &socket.DeleteMessageTask{
+			MessageId: messageID,
+		}
+		if _, err := c.Messagix.ExecuteTasks(c.ctx, task); err != nil {
+			return nil, err
+		}
+		return &SendMessageResult{}, nil
+	})
 	return err
 }
 
-// SendTypingIndicator sends a typing indicator
+// SendTypingIndicator sends a typing indicator.
+// If threadType is 0, the thread type and group flag are taken from the state mirror.
 func (c *Client) SendTypingIndicator(threadID int64, isTyping bool, isGroup bool, threadType int64) error {
+	if threadType == 0 {
+		if thread := c.state.getThread(threadID); thread != nil {
+			threadType = int64(thread.Type)
+			isGroup = thread.IsGroup
+		}
+	}
 	typingVal, groupVal := int64(0), int64(0)
 	if isTyping {
+		// Only starts count against the budget, so a stop is never held back
+		if err := c.rateLimit(RateCategoryTyping, threadID); err != nil {
+			return err
+		}
 		typingVal = 1
 	}
 	if isGroup {
@@ -315,13 +406,17 @@ func (c *Client) SendE2EETyping(chatJIDStr string, isTyping bool) error {
 
 	presence := waTypes.ChatPresencePaused
 	if isTyping {
+		if err := c.rateLimitChat(RateCategoryTyping, chatJID); err != nil {
+			return err
+		}
 		presence = waTypes.ChatPresenceComposing
 	}
 	return c.E2EE.SendChatPresence(context.Background(), chatJID, presence, waTypes.ChatPresenceMediaText)
 }
 
-// EditE2EEMessage edits an E2EE message
-func (c *Client) EditE2EEMessage(chatJIDStr, messageID, newText string) error {
+// EditE2EEMessage edits an E2EE message. With an idempotency key, the edit's
+// message ID is derived from it and a retry doesn't send it again.
+func (c *Client) EditE2EEMessage(chatJIDStr, messageID, newText, idempotencyKey string) error {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return ErrE2EENotConnected
 	}
@@ -330,6 +425,17 @@ func (c *Client) EditE2EEMessage(chatJIDStr, messageID, newText string) error {
 	if err != nil {
 		return err
 	}
+	key := scopedKey("edit", idempotencyKey)
+	_, err = c.idempotency.do(key, func() (*SendMessageResult, error) {
+		return c.editE2EEMessage(chatJID, messageID, newText, key)
+	})
+	return err
+}
+
+func (c *Client) editE2EEMessage(chatJID waTypes.JID, messageID, newText, idempotencyKey string) (*SendMessageResult, error) {
+	if err := c.rateLimitChat(RateCategoryMessages, chatJID); err != nil {
+		return nil, err
+	}
 
 	msgKey := c.E2EE.BuildMessageKey(chatJID, waTypes.EmptyJID, messageID)
 	ts := time.Now().UnixMilli()
@@ -349,13 +455,25 @@ func (c *Client) EditE2EEMessage(chatJIDStr, messageID, newText string) error {
 		},
 	}
 
-	editID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	_, err = c.E2EE.SendFBMessage(c.ctx, chatJID, editMsg, nil, whatsmeow.SendRequestExtra{ID: editID})
-	return err
+	editID := c.newE2EEMessageID(idempotencyKey)
+	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, editMsg, nil, whatsmeow.SendRequestExtra{ID: editID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Our own E2EE edits aren't echoed back, so update the mirror and archive here
+	previous, editCount := c.state.applyEdit(messageID, newText, 0)
+	evt := newMessageEditEvent(messageID, newText, editCount, previous)
+	evt.ChatJID = chatJID.String()
+	evt.SenderID = c.FBID
+	evt.IsE2EE = true
+	c.archiveEvent(EventTypeMessageEdit, evt)
+	return &SendMessageResult{MessageID: editID, TimestampMs: resp.Timestamp.UnixMilli()}, nil
 }
 
-// UnsendE2EEMessage unsends/deletes an E2EE message
-func (c *Client) UnsendE2EEMessage(chatJIDStr, messageID string) error {
+// UnsendE2EEMessage unsends/deletes an E2EE message. With an idempotency key, the
+// revoke's message ID is derived from it and a retry doesn't send it again.
+func (c *Client) UnsendE2EEMessage(chatJIDStr, messageID, idempotencyKey string) error {
 	if c.E2EE == nil || !c.E2EE.IsConnected() {
 		return ErrE2EENotConnected
 	}
@@ -364,6 +482,17 @@ func (c *Client) UnsendE2EEMessage(chatJIDStr, messageID string) error {
 	if err != nil {
 		return err
 	}
+	key := scopedKey("unsend", idempotencyKey)
+	_, err = c.idempotency.do(key, func() (*SendMessageResult, error) {
+		return c.unsendE2EEMessage(chatJID, messageID, key)
+	})
+	return err
+}
+
+func (c *Client) unsendE2EEMessage(chatJID waTypes.JID, messageID, idempotencyKey string) (*SendMessageResult, error) {
+	if err := c.rateLimitChat(RateCategoryMessages, chatJID); err != nil {
+		return nil, err
+	}
 
 	msgKey := c.E2EE.BuildMessageKey(chatJID, waTypes.EmptyJID, messageID)
 	revokeMsg := &waConsumerApplication.ConsumerApplication{
@@ -378,7 +507,42 @@ func (c *Client) UnsendE2EEMessage(chatJIDStr, messageID string) error {
 		},
 	}
 
-	revokeID := strconv.FormatInt(time.Now().UnixNano(), 10)
-	_, err = c.E2EE.SendFBMessage(c.ctx, chatJID, revokeMsg, nil, whatsmeow.SendRequestExtra{ID: revokeID})
-	return err
+	revokeID := c.newE2EEMessageID(idempotencyKey)
+	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, revokeMsg, nil, whatsmeow.SendRequestExtra{ID: revokeID})
+	if err != nil {
+		return nil, err
+	}
+
+	threadID, _ := strconv.ParseInt(chatJID.User, 10, 64)
+	evt := newMessageUnsendEvent(messageID, threadID, c.state.applyUnsend(messageID))
+	evt.ChatJID = chatJID.String()
+	evt.SenderID = c.FBID
+	evt.IsE2EE = true
+	c.archiveEvent(EventTypeMessageUnsend, evt)
+	return &SendMessageResult{MessageID: revokeID, TimestampMs: resp.Timestamp.UnixMilli()}, nil
+}
+
+// MarkE2EERead sends read receipts for E2EE messages. In groups, senderJIDStr is
+// the participant who sent them. Delivery receipts are sent by whatsmeow when
+// messages are decrypted, and shown to senders when AutoMarkRead is on.
+func (c *Client) MarkE2EERead(chatJIDStr string, messageIDs []string, senderJIDStr string) error {
+	if c.E2EE == nil || !c.E2EE.IsConnected() {
+		return ErrE2EENotConnected
+	}
+	if len(messageIDs) == 0 {
+		return fmt.Errorf("messageIds is required")
+	}
+
+	chatJID, err := parseJID(chatJIDStr)
+	if err != nil {
+		return err
+	}
+	senderJID, err := parseJID(senderJIDStr)
+	if err != nil {
+		return err
+	}
+	if chatJID.Server == waTypes.GroupServer && senderJID.IsEmpty() {
+		return fmt.Errorf("senderJid is required in group chats")
+	}
+	return c.E2EE.MarkRead(c.ctx, messageIDs, time.Now(), chatJID, senderJID)
 }
@@ -0,0 +1,665 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// OutboxKind is the kind of send an outbox item performs
+type OutboxKind string
+
+const (
+	OutboxKindMessage      OutboxKind = "message"      // SendMessageOptions
+	OutboxKindMedia        OutboxKind = "media"        // SendMediaOptions
+	OutboxKindImage        OutboxKind = "image"        // SendImageOptions
+	OutboxKindVideo        OutboxKind = "video"        // SendVideoOptions
+	OutboxKindVoice        OutboxKind = "voice"        // SendVoiceOptions
+	OutboxKindFile         OutboxKind = "file"         // SendFileOptions
+	OutboxKindSticker      OutboxKind = "sticker"      // SendStickerOptions
+	OutboxKindE2EEImage    OutboxKind = "e2eeImage"    // SendE2EEImageOptions
+	OutboxKindE2EEVideo    OutboxKind = "e2eeVideo"    // SendE2EEVideoOptions
+	OutboxKindE2EEAudio    OutboxKind = "e2eeAudio"    // SendE2EEAudioOptions
+	OutboxKindE2EEDocument OutboxKind = "e2eeDocument" // SendE2EEDocumentOptions
+	OutboxKindE2EESticker  OutboxKind = "e2eeSticker"  // SendE2EEStickerOptions
+	OutboxKindReaction     OutboxKind = "reaction"     // OutboxReaction
+	OutboxKindE2EEReaction OutboxKind = "e2eeReaction" // OutboxE2EEReaction
+	OutboxKindEdit         OutboxKind = "edit"         // OutboxEdit
+	OutboxKindE2EEEdit     OutboxKind = "e2eeEdit"     // OutboxE2EEEdit
+	OutboxKindUnsend       OutboxKind = "unsend"       // OutboxUnsend
+	OutboxKindE2EEUnsend   OutboxKind = "e2eeUnsend"   // OutboxE2EEUnsend
+)
+
+// outboxKinds are the kinds the outbox knows how to send
+var outboxKinds = map[OutboxKind]bool{
+	OutboxKindMessage:      true,
+	OutboxKindMedia:        true,
+	OutboxKindImage:        true,
+	OutboxKindVideo:        true,
+	OutboxKindVoice:        true,
+	OutboxKindFile:         true,
+	OutboxKindSticker:      true,
+	OutboxKindE2EEImage:    true,
+	OutboxKindE2EEVideo:    true,
+	OutboxKindE2EEAudio:    true,
+	OutboxKindE2EEDocument: true,
+	OutboxKindE2EESticker:  true,
+	OutboxKindReaction:     true,
+	OutboxKindE2EEReaction: true,
+	OutboxKindEdit:         true,
+	OutboxKindE2EEEdit:     true,
+	OutboxKindUnsend:       true,
+	OutboxKindE2EEUnsend:   true,
+}
+
+// OutboxStatus is the delivery state of an outbox item
+type OutboxStatus string
+
+const (
+	OutboxStatusQueued    OutboxStatus = "queued"
+	OutboxStatusSending   OutboxStatus = "sending"
+	OutboxStatusSent      OutboxStatus = "sent"
+	OutboxStatusFailed    OutboxStatus = "failed" // Failed with a permanent error, or gave up after outboxMaxAttempts
+	OutboxStatusCancelled OutboxStatus = "cancelled"
+)
+
+const (
+	outboxMaxAttempts  = 10
+	outboxBaseBackoff  = time.Second
+	outboxMaxBackoff   = 5 * time.Minute
+	outboxPollInterval = 30 * time.Second // How often to recheck the connection while items wait
+	outboxRetention    = 24 * time.Hour   // How long finished items stay queryable
+)
+
+// Payloads for the outbox kinds whose client methods take positional arguments
+
+// OutboxReaction is the payload of a "reaction" item
+type OutboxReaction struct {
+	ThreadID       int64  `json:"threadId"`
+	MessageID      string `json:"messageId"`
+	Emoji          string `json:"emoji"` // Empty to remove
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxE2EEReaction is the payload of an "e2eeReaction" item
+type OutboxE2EEReaction struct {
+	ChatJID        string `json:"chatJid"`
+	MessageID      string `json:"messageId"`
+	SenderJID      string `json:"senderJid"`
+	Emoji          string `json:"emoji"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxEdit is the payload of an "edit" item
+type OutboxEdit struct {
+	ThreadID       int64  `json:"threadId,omitempty"` // Orders the edit with other sends to the thread, looked up if unset
+	MessageID      string `json:"messageId"`
+	NewText        string `json:"newText"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxE2EEEdit is the payload of an "e2eeEdit" item
+type OutboxE2EEEdit struct {
+	ChatJID        string `json:"chatJid"`
+	MessageID      string `json:"messageId"`
+	NewText        string `json:"newText"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxUnsend is the payload of an "unsend" item
+type OutboxUnsend struct {
+	ThreadID       int64  `json:"threadId,omitempty"` // Orders the unsend with other sends to the thread, looked up if unset
+	MessageID      string `json:"messageId"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxE2EEUnsend is the payload of an "e2eeUnsend" item
+type OutboxE2EEUnsend struct {
+	ChatJID        string `json:"chatJid"`
+	MessageID      string `json:"messageId"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+// OutboxItem is a queued send and its delivery state
+type OutboxItem struct {
+	ID              string             `json:"id"`
+	Kind            OutboxKind         `json:"kind"`
+	ThreadKey       string             `json:"threadKey"`         // "thread:<id>", "chat:<jid>" or "message:<id>", items with the same key are sent in order
+	Payload         json.RawMessage    `json:"payload,omitempty"` // Stored in its own file when persisted, dropped once finished
+	Status          OutboxStatus       `json:"status"`
+	Attempts        int                `json:"attempts"`
+	LastError       string             `json:"lastError,omitempty"`
+	NextAttemptAtMs int64              `json:"nextAttemptAtMs,omitempty"`
+	Result          *SendMessageResult `json:"result,omitempty"` // Set when a message-producing item is sent
+	CreatedAtMs     int64              `json:"createdAtMs"`
+	UpdatedAtMs     int64              `json:"updatedAtMs"`
+}
+
+func (item *OutboxItem) isFinished() bool {
+	return item.Status == OutboxStatusSent || item.Status == OutboxStatusFailed || item.Status == OutboxStatusCancelled
+}
+
+// clone copies an item for callers, without the payload which may hold media
+func (item *OutboxItem) clone() *OutboxItem {
+	clone := *item
+	clone.Payload = nil
+	return &clone
+}
+
+// outbox holds sends until they can be delivered, optionally persisted to a JSON
+// file. Payloads, which may hold media, are kept in one file per item next to it,
+// so state changes only rewrite the small index.
+type outbox struct {
+	mu    sync.Mutex
+	path  string // Empty for memory only
+	items map[string]*OutboxItem
+	order []string // Item IDs in enqueue order
+	wake  chan struct{}
+}
+
+func newOutbox(path string) (*outbox, error) {
+	ob := &outbox{
+		path:  path,
+		items: make(map[string]*OutboxItem),
+		wake:  make(chan struct{}, 1),
+	}
+	if path == "" {
+		return ob, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ob, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	var items []*OutboxItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("corrupt outbox: %w", err)
+	}
+	for _, item := range items {
+		// A send interrupted by a restart is sent again. Idempotency keys are only
+		// remembered in memory, so it's delivered twice if the first attempt went out.
+		if item.Status == OutboxStatusSending {
+			item.Status = OutboxStatusQueued
+		}
+		if !item.isFinished() {
+			if item.Payload, err = os.ReadFile(ob.payloadPath(item.ID)); err != nil {
+				item.Status = OutboxStatusFailed
+				item.LastError = fmt.Sprintf("failed to read payload: %v", err)
+			}
+		}
+		ob.items[item.ID] = item
+		ob.order = append(ob.order, item.ID)
+	}
+	return ob, nil
+}
+
+// payloadPath is the file an item's payload is persisted in
+func (ob *outbox) payloadPath(id string) string {
+	return filepath.Join(ob.path+".payloads", id+".json")
+}
+
+// saveLocked persists the outbox index if a path is set
+func (ob *outbox) saveLocked() error {
+	if ob.path == "" {
+		return nil
+	}
+	items := make([]*OutboxItem, 0, len(ob.order))
+	for _, id := range ob.order {
+		items = append(items, ob.items[id].clone())
+	}
+	return writeJSONFile(ob.path, items)
+}
+
+// releasePayloadLocked drops the payload of a finished item
+func (ob *outbox) releasePayloadLocked(item *OutboxItem) {
+	item.Payload = nil
+	if ob.path != "" {
+		// A file left behind is only disk space, finished items don't read it
+		os.Remove(ob.payloadPath(item.ID))
+	}
+}
+
+// writeJSONFile writes v to a temporary file and renames it into place, so a
+// crash never leaves a partially written file
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// pruneLocked drops finished items past the retention period
+func (ob *outbox) pruneLocked(now time.Time) {
+	cutoff := now.Add(-outboxRetention).UnixMilli()
+	kept := ob.order[:0]
+	for _, id := range ob.order {
+		item := ob.items[id]
+		if item.isFinished() && item.UpdatedAtMs < cutoff {
+			delete(ob.items, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	ob.order = kept
+}
+
+func (ob *outbox) notify() {
+	select {
+	case ob.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (ob *outbox) add(item *OutboxItem) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.pruneLocked(time.Now())
+	if ob.path != "" {
+		if err := writeJSONFile(ob.payloadPath(item.ID), item.Payload); err != nil {
+			return fmt.Errorf("failed to save outbox payload: %w", err)
+		}
+	}
+	ob.items[item.ID] = item
+	ob.order = append(ob.order, item.ID)
+	if err := ob.saveLocked(); err != nil {
+		delete(ob.items, item.ID)
+		ob.order = ob.order[:len(ob.order)-1]
+		ob.releasePayloadLocked(item)
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	ob.notify()
+	return nil
+}
+
+func (ob *outbox) get(id string) *OutboxItem {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if item, ok := ob.items[id]; ok {
+		return item.clone()
+	}
+	return nil
+}
+
+func (ob *outbox) list(status OutboxStatus) []*OutboxItem {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	items := []*OutboxItem{}
+	for _, id := range ob.order {
+		if item := ob.items[id]; status == "" || item.Status == status {
+			items = append(items, item.clone())
+		}
+	}
+	return items
+}
+
+func (ob *outbox) cancel(id string) (*OutboxItem, error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	item, ok := ob.items[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown outbox item: %s", id)
+	}
+	if item.Status != OutboxStatusQueued {
+		return nil, fmt.Errorf("outbox item %s is %s and can't be cancelled", id, item.Status)
+	}
+	item.Status = OutboxStatusCancelled
+	item.NextAttemptAtMs = 0
+	item.UpdatedAtMs = timeNowMs()
+	if err := ob.saveLocked(); err != nil {
+		return nil, fmt.Errorf("failed to save outbox: %w", err)
+	}
+	ob.releasePayloadLocked(item)
+	// The next item in the thread may be sendable now
+	ob.notify()
+	return item.clone(), nil
+}
+
+// next claims the oldest due item at the head of a thread queue whose connection
+// is up. If none is ready it returns how long to wait before checking again.
+func (ob *outbox) next(canSend func(*OutboxItem) bool) (*OutboxItem, time.Duration, error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	now := time.Now()
+	ob.pruneLocked(now)
+
+	wait := outboxPollInterval
+	blocked := make(map[string]bool)
+	for _, id := range ob.order {
+		item := ob.items[id]
+		if item.isFinished() || blocked[item.ThreadKey] {
+			continue
+		}
+		// Later items in the thread wait for this one
+		blocked[item.ThreadKey] = true
+		if item.Status != OutboxStatusQueued || !canSend(item) {
+			continue
+		}
+		if delay := time.UnixMilli(item.NextAttemptAtMs).Sub(now); delay > 0 {
+			wait = min(wait, delay)
+			continue
+		}
+		item.Status = OutboxStatusSending
+		item.UpdatedAtMs = now.UnixMilli()
+		claimed := *item
+		return &claimed, 0, ob.saveLocked()
+	}
+	return nil, wait, nil
+}
+
+// finish records the outcome of an attempt, scheduling a retry with backoff if the
+// failure is transient
+func (ob *outbox) finish(id string, result *SendMessageResult, sendErr error, transient bool) (*OutboxItem, error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	item, ok := ob.items[id]
+	if !ok {
+		return nil, nil
+	}
+	now := time.Now()
+	item.Attempts++
+	item.UpdatedAtMs = now.UnixMilli()
+	item.NextAttemptAtMs = 0
+	if sendErr == nil {
+		item.Status = OutboxStatusSent
+		item.Result = result
+		item.LastError = ""
+	} else {
+		item.LastError = sendErr.Error()
+		if !transient || item.Attempts >= outboxMaxAttempts {
+			item.Status = OutboxStatusFailed
+		} else {
+			item.Status = OutboxStatusQueued
+			item.NextAttemptAtMs = now.Add(outboxBackoff(item.Attempts)).UnixMilli()
+		}
+	}
+	err := ob.saveLocked()
+	if err == nil && item.isFinished() {
+		ob.releasePayloadLocked(item)
+	}
+	return item.clone(), err
+}
+
+// outboxBackoff is the delay before retrying after the given number of failed attempts
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff << (attempts - 1)
+	if backoff <= 0 || backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
+
+// isTransientSendError reports whether a failed send may succeed when retried:
+// timeouts, transport errors and sends that failed because the connection is down.
+// Anything else, like an invalid payload or a send the server rejected, would fail
+// the same way again.
+func (c *Client) isTransientSendError(err error, isE2EE bool) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, ErrE2EENotConnected),
+		errors.Is(err, whatsmeow.ErrNotConnected),
+		errors.Is(err, whatsmeow.ErrIQTimedOut),
+		errors.Is(err, whatsmeow.ErrIQDisconnected),
+		errors.Is(err, ErrRateLimited),
+		errors.As(err, &netErr):
+		return true
+	}
+	return !c.canSend(isE2EE)
+}
+
+// OutboxEnqueueOptions for queueing a send
+type OutboxEnqueueOptions struct {
+	Kind    OutboxKind      `json:"kind"`
+	Payload json.RawMessage `json:"payload"` // Options of the matching send, see the OutboxKind constants
+}
+
+// outboxTarget holds the payload fields that decide which thread an item belongs to
+type outboxTarget struct {
+	Thread      *ThreadRef `json:"thread"`
+	ThreadID    int64      `json:"threadId"`
+	ChatJID     string     `json:"chatJid"`
+	E2EEChatJID string     `json:"e2eeChatJid"`
+	MessageID   string     `json:"messageId"`
+}
+
+// outboxThreadKey returns the queue an item is ordered in. Messages are routed
+// like SendMessage routes them, so E2EE threads queue on the E2EE connection.
+func (c *Client) outboxThreadKey(kind OutboxKind, target *outboxTarget) (string, error) {
+	ref := ThreadRef{ThreadID: target.ThreadID, ChatJID: target.ChatJID}
+	if kind == OutboxKindMessage {
+		if target.Thread != nil {
+			ref = *target.Thread
+		} else if target.E2EEChatJID != "" {
+			ref.ChatJID = target.E2EEChatJID
+		}
+	}
+	if ref.ThreadID == 0 && ref.ChatJID == "" {
+		if target.MessageID == "" {
+			return "", fmt.Errorf("payload has no thread, chat or message to send to")
+		}
+		msg := c.state.getMessage(target.MessageID)
+		if msg == nil || msg.ThreadID == 0 {
+			return "message:" + target.MessageID, nil
+		}
+		ref = ThreadRef{ThreadID: msg.ThreadID, ChatJID: msg.ChatJID}
+	}
+	thread, err := c.ResolveThread(&ref)
+	if err != nil {
+		return "", err
+	}
+	if thread.IsE2EE {
+		return "chat:" + thread.ChatJID, nil
+	}
+	return "thread:" + strconv.FormatInt(thread.ThreadID, 10), nil
+}
+
+// EnqueueOutbox queues a send for delivery. Items are sent in order per thread as
+// soon as the needed connection is up. Transient failures are retried with backoff,
+// other errors fail the item at once. Items get an idempotency key if they don't
+// have one, so a retry after an ambiguous failure doesn't send twice while the
+// client runs. Keys aren't persisted: a send interrupted by a restart is sent
+// again, so delivery is at least once.
+func (c *Client) EnqueueOutbox(opts *OutboxEnqueueOptions) (*OutboxItem, error) {
+	if !outboxKinds[opts.Kind] {
+		return nil, fmt.Errorf("unknown outbox kind: %s", opts.Kind)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(opts.Payload, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("invalid payload for %s: expected an object", opts.Kind)
+	}
+	var target outboxTarget
+	if err := json.Unmarshal(opts.Payload, &target); err != nil {
+		return nil, fmt.Errorf("invalid payload for %s: %w", opts.Kind, err)
+	}
+	threadKey, err := c.outboxThreadKey(opts.Kind, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.FormatInt(c.ids.next(), 10)
+	payload := opts.Payload
+	if key, ok := fields["idempotencyKey"]; !ok || string(key) == `""` || string(key) == "null" {
+		fields["idempotencyKey"], _ = json.Marshal("outbox:" + id)
+		if payload, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	now := timeNowMs()
+	item := &OutboxItem{
+		ID:          id,
+		Kind:        opts.Kind,
+		ThreadKey:   threadKey,
+		Payload:     payload,
+		Status:      OutboxStatusQueued,
+		CreatedAtMs: now,
+		UpdatedAtMs: now,
+	}
+	if err := c.outbox.add(item); err != nil {
+		return nil, err
+	}
+	clone := item.clone()
+	c.emitEvent(EventTypeOutboxUpdate, clone)
+	return clone, nil
+}
+
+// GetOutboxItem returns the state of an outbox item
+func (c *Client) GetOutboxItem(id string) (*OutboxItem, error) {
+	if item := c.outbox.get(id); item != nil {
+		return item, nil
+	}
+	return nil, fmt.Errorf("unknown outbox item: %s", id)
+}
+
+// ListOutbox returns outbox items in enqueue order, optionally only those with a status
+func (c *Client) ListOutbox(status OutboxStatus) []*OutboxItem {
+	return c.outbox.list(status)
+}
+
+// CancelOutboxItem cancels an item that hasn't been sent yet
+func (c *Client) CancelOutboxItem(id string) (*OutboxItem, error) {
+	item, err := c.outbox.cancel(id)
+	if err != nil {
+		return nil, err
+	}
+	c.emitEvent(EventTypeOutboxUpdate, item)
+	return item, nil
+}
+
+// outboxCanSend reports whether the connection an item needs is up
+func (c *Client) outboxCanSend(item *OutboxItem) bool {
+	return c.canSend(strings.HasPrefix(item.ThreadKey, "chat:"))
+}
+
+// canSend reports whether the E2EE or regular connection is up
+func (c *Client) canSend(isE2EE bool) bool {
+	if isE2EE {
+		return c.IsE2EEConnected()
+	}
+	return c.FBID != 0 && c.Messagix.IsConnected()
+}
+
+// runOutbox delivers outbox items until the client is closed
+func (c *Client) runOutbox() {
+	for {
+		item, wait, err := c.outbox.next(c.outboxCanSend)
+		if err != nil {
+			c.Logger.Warn().Err(err).Msg("Failed to save outbox")
+		}
+		if item != nil {
+			c.emitEvent(EventTypeOutboxUpdate, item.clone())
+			c.deliverOutboxItem(item)
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-c.outbox.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (c *Client) deliverOutboxItem(item *OutboxItem) {
+	result, sendErr := c.sendOutboxItem(item)
+	if sendErr != nil {
+		c.Logger.Debug().Err(sendErr).Str("id", item.ID).Str("kind", string(item.Kind)).Msg("Outbox send failed")
+	}
+	transient := sendErr != nil && c.isTransientSendError(sendErr, strings.HasPrefix(item.ThreadKey, "chat:"))
+	updated, err := c.outbox.finish(item.ID, result, sendErr, transient)
+	if err != nil {
+		c.Logger.Warn().Err(err).Msg("Failed to save outbox")
+	}
+	if updated != nil {
+		c.emitEvent(EventTypeOutboxUpdate, updated)
+	}
+}
+
+// outboxSend decodes an item's payload into the options of a send and runs it
+func outboxSend[T any](payload json.RawMessage, send func(*T) (*SendMessageResult, error)) (*SendMessageResult, error) {
+	var opts T
+	if err := json.Unmarshal(payload, &opts); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	return send(&opts)
+}
+
+func (c *Client) sendOutboxItem(item *OutboxItem) (*SendMessageResult, error) {
+	switch item.Kind {
+	case OutboxKindMessage:
+		return outboxSend(item.Payload, c.SendMessage)
+	case OutboxKindMedia:
+		return outboxSend(item.Payload, c.SendMedia)
+	case OutboxKindImage:
+		return outboxSend(item.Payload, c.SendImage)
+	case OutboxKindVideo:
+		return outboxSend(item.Payload, c.SendVideo)
+	case OutboxKindVoice:
+		return outboxSend(item.Payload, c.SendVoice)
+	case OutboxKindFile:
+		return outboxSend(item.Payload, c.SendFile)
+	case OutboxKindSticker:
+		return outboxSend(item.Payload, c.SendSticker)
+	case OutboxKindE2EEImage:
+		return outboxSend(item.Payload, c.SendE2EEImage)
+	case OutboxKindE2EEVideo:
+		return outboxSend(item.Payload, c.SendE2EEVideo)
+	case OutboxKindE2EEAudio:
+		return outboxSend(item.Payload, c.SendE2EEAudio)
+	case OutboxKindE2EEDocument:
+		return outboxSend(item.Payload, c.SendE2EEDocument)
+	case OutboxKindE2EESticker:
+		return outboxSend(item.Payload, c.SendE2EESticker)
+	case OutboxKindReaction:
+		return outboxSend(item.Payload, func(p *OutboxReaction) (*SendMessageResult, error) {
+			return nil, c.SendReaction(p.ThreadID, p.MessageID, p.Emoji, p.IdempotencyKey)
+		})
+	case OutboxKindE2EEReaction:
+		return outboxSend(item.Payload, func(p *OutboxE2EEReaction) (*SendMessageResult, error) {
+			return nil, c.SendE2EEReaction(p.ChatJID, p.MessageID, p.SenderJID, p.Emoji, p.IdempotencyKey)
+		})
+	case OutboxKindEdit:
+		return outboxSend(item.Payload, func(p *OutboxEdit) (*SendMessageResult, error) {
+			return nil, c.EditMessage(p.MessageID, p.NewText, p.IdempotencyKey)
+		})
+	case OutboxKindE2EEEdit:
+		return outboxSend(item.Payload, func(p *OutboxE2EEEdit) (*SendMessageResult, error) {
+			return nil, c.EditE2EEMessage(p.ChatJID, p.MessageID, p.NewText, p.IdempotencyKey)
+		})
+	case OutboxKindUnsend:
+		return outboxSend(item.Payload, func(p *OutboxUnsend) (*SendMessageResult, error) {
+			return nil, c.UnsendMessage(p.MessageID, p.IdempotencyKey)
+		})
+	case OutboxKindE2EEUnsend:
+		return outboxSend(item.Payload, func(p *OutboxE2EEUnsend) (*SendMessageResult, error) {
+			return nil, c.UnsendE2EEMessage(p.ChatJID, p.MessageID, p.IdempotencyKey)
+		})
+	}
+	return nil, fmt.Errorf("unknown outbox kind: %s", item.Kind)
+}
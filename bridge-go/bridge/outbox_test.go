@@ -0,0 +1,97 @@
+package bridge
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, outboxMaxBackoff},
+		{100, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxFinish(t *testing.T) {
+	errSend := errors.New("send failed")
+	tests := []struct {
+		name        string
+		attempts    int // before this one
+		sendErr     error
+		transient   bool
+		wantStatus  OutboxStatus
+		wantRetry   bool
+		wantPayload bool
+	}{
+		{"sent", 0, nil, false, OutboxStatusSent, false, false},
+		{"permanent error", 0, errSend, false, OutboxStatusFailed, false, false},
+		{"transient error", 0, errSend, true, OutboxStatusQueued, true, true},
+		{"transient error on the last attempt", outboxMaxAttempts - 1, errSend, true, OutboxStatusFailed, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob, err := newOutbox("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := &OutboxItem{ID: "1", Kind: OutboxKindMessage, ThreadKey: "thread:1", Payload: []byte(`{}`), Status: OutboxStatusQueued}
+			item.Attempts = tt.attempts
+			if err := ob.add(item); err != nil {
+				t.Fatal(err)
+			}
+			updated, err := ob.finish("1", &SendMessageResult{MessageID: "mid.1"}, tt.sendErr, tt.transient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tt.wantStatus || updated.Attempts != tt.attempts+1 {
+				t.Fatalf("status %s after %d attempts, want %s after %d", updated.Status, updated.Attempts, tt.wantStatus, tt.attempts+1)
+			}
+			if (updated.NextAttemptAtMs != 0) != tt.wantRetry {
+				t.Fatalf("nextAttemptAtMs = %d, want retry %v", updated.NextAttemptAtMs, tt.wantRetry)
+			}
+			if (ob.items["1"].Payload != nil) != tt.wantPayload {
+				t.Fatalf("payload kept = %v, want %v", ob.items["1"].Payload != nil, tt.wantPayload)
+			}
+			if tt.sendErr != nil && updated.LastError != tt.sendErr.Error() {
+				t.Fatalf("lastError = %q", updated.LastError)
+			}
+		})
+	}
+}
+
+func TestOutboxNextKeepsThreadOrder(t *testing.T) {
+	ob, err := newOutbox("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []*OutboxItem{
+		{ID: "1", ThreadKey: "thread:1", Status: OutboxStatusQueued, NextAttemptAtMs: time.Now().Add(time.Hour).UnixMilli()},
+		{ID: "2", ThreadKey: "thread:1", Status: OutboxStatusQueued},
+		{ID: "3", ThreadKey: "thread:2", Status: OutboxStatusQueued},
+	} {
+		if err := ob.add(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	canSend := func(*OutboxItem) bool { return true }
+	item, _, err := ob.next(canSend)
+	if err != nil || item == nil || item.ID != "3" {
+		t.Fatalf("next = %+v, %v, want item 3 while item 1 waits to retry", item, err)
+	}
+	if item, wait, _ := ob.next(canSend); item != nil || wait <= 0 {
+		t.Fatalf("next = %+v, wait %s, want nothing ready", item, wait)
+	}
+}
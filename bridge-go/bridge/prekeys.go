@@ -0,0 +1,60 @@
+package bridge
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// The signed prekey isn't rotated: whatsmeow only loads the current signed prekey, so peers
+// holding a bundle with the previous one would fail to start sessions until it expires.
+// One-time prekeys aren't pruned either, whatsmeow removes each one when it's consumed.
+
+const (
+	// preKeyMaintenanceDelay postpones the first check until whatsmeow's post-connect upload has settled
+	// (it skips uploads for 10 minutes after the previous one)
+	preKeyMaintenanceDelay = 15 * time.Minute
+	// preKeyMaintenanceInterval is how often the server prekey count is checked
+	preKeyMaintenanceInterval = 6 * time.Hour
+)
+
+// runPreKeyMaintenance periodically replenishes the prekeys on the server, until the client is closed
+func (c *Client) runPreKeyMaintenance() {
+	timer := time.NewTimer(preKeyMaintenanceDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		c.maintainPreKeys()
+		timer.Reset(preKeyMaintenanceInterval)
+	}
+}
+
+// maintainPreKeys runs one prekey maintenance pass
+func (c *Client) maintainPreKeys() {
+	if !c.IsE2EEConnected() {
+		return
+	}
+	internals := c.E2EE.DangerousInternals()
+
+	serverCount, err := internals.GetServerPreKeyCount(c.ctx)
+	if err != nil {
+		c.Logger.Warn().Err(err).Msg("Failed to get server prekey count")
+		return
+	}
+	if serverCount >= whatsmeow.MinPreKeyCount {
+		return
+	}
+
+	c.Logger.Info().Int("serverCount", serverCount).Msg("Uploading prekeys")
+	// UploadPreKeys doesn't report errors, so the upload is confirmed by counting again
+	internals.UploadPreKeys(c.ctx, false)
+	if serverCount, err = internals.GetServerPreKeyCount(c.ctx); err != nil {
+		c.Logger.Warn().Err(err).Msg("Failed to get server prekey count after upload")
+	} else if serverCount < whatsmeow.MinPreKeyCount {
+		c.Logger.Warn().Int("serverCount", serverCount).Msg("Prekey upload didn't replenish the server, retrying next pass")
+	}
+}
@@ -0,0 +1,260 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// ErrRateLimited is returned when a send is throttled and the limiter is set to fail fast
+var ErrRateLimited = errors.New("rate limited")
+
+// RateCategory is a class of outgoing traffic with its own budget
+type RateCategory string
+
+const (
+	RateCategoryMessages  RateCategory = "messages" // Messages, media, forwards, edits and unsends
+	RateCategoryReactions RateCategory = "reactions"
+	RateCategoryTyping    RateCategory = "typing"
+)
+
+// RateBudget is a token bucket for the account and one for each thread
+type RateBudget struct {
+	PerSecond       float64 `json:"perSecond"`       // Account-wide refill rate
+	Burst           int     `json:"burst"`           // Account-wide bucket size
+	ThreadPerSecond float64 `json:"threadPerSecond"` // Refill rate per thread
+	ThreadBurst     int     `json:"threadBurst"`     // Bucket size per thread
+}
+
+// RateLimitConfig configures the outgoing rate limiter. Limiting is opt-in: it's
+// only on when a config is given, and budgets left unset use the defaults.
+type RateLimitConfig struct {
+	Disabled  bool        `json:"disabled,omitempty"`
+	FailFast  bool        `json:"failFast,omitempty"`  // Return ErrRateLimited instead of waiting for a token
+	MaxWaitMs int         `json:"maxWaitMs,omitempty"` // Longest a call waits before failing anyway (default 30s)
+	Messages  *RateBudget `json:"messages,omitempty"`
+	Reactions *RateBudget `json:"reactions,omitempty"`
+	Typing    *RateBudget `json:"typing,omitempty"`
+}
+
+var defaultRateBudgets = map[RateCategory]RateBudget{
+	RateCategoryMessages:  {PerSecond: 2, Burst: 10, ThreadPerSecond: 1, ThreadBurst: 5},
+	RateCategoryReactions: {PerSecond: 3, Burst: 10, ThreadPerSecond: 2, ThreadBurst: 5},
+	RateCategoryTyping:    {PerSecond: 2, Burst: 5, ThreadPerSecond: 0.5, ThreadBurst: 2},
+}
+
+const (
+	defaultRateLimitMaxWait = 30 * time.Second
+	maxThreadBuckets        = 1000 // Least recently used thread buckets are dropped beyond this many
+)
+
+// RateLimitedEvent is emitted when a send is delayed or rejected by the rate limiter
+type RateLimitedEvent struct {
+	Category RateCategory `json:"category"`
+	ThreadID int64        `json:"threadId,omitempty"`
+	WaitMs   int64        `json:"waitMs"`   // How long the call waits, or would have to wait if rejected
+	Rejected bool         `json:"rejected"` // The call failed with ErrRateLimited
+}
+
+// tokenBucket refills continuously up to its burst size. Tokens may go negative,
+// which reserves future tokens for callers that are waiting.
+type tokenBucket struct {
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time // Last refill
+	lastUsed time.Time // Last time a token was taken
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now, lastUsed: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// delay returns how long until a token is available
+func (b *tokenBucket) delay(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// hasReservations reports whether callers are still waiting for tokens they took
+func (b *tokenBucket) hasReservations(now time.Time) bool {
+	b.refill(now)
+	return b.tokens < 0
+}
+
+// take removes a token, which may reserve a future one
+func (b *tokenBucket) take(now time.Time) {
+	b.tokens--
+	b.lastUsed = now
+}
+
+// refund returns a token that was taken but not used
+func (b *tokenBucket) refund(now time.Time) {
+	b.refill(now)
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+// rateCategoryLimiter holds the account and per-thread buckets of one category
+type rateCategoryLimiter struct {
+	budget  RateBudget
+	account *tokenBucket
+	threads map[int64]*tokenBucket
+}
+
+// evictOldest drops the least recently used thread bucket that has no callers waiting on it
+func (cl *rateCategoryLimiter) evictOldest(now time.Time) {
+	var oldestID int64
+	var oldest *tokenBucket
+	for id, b := range cl.threads {
+		if !b.hasReservations(now) && (oldest == nil || b.lastUsed.Before(oldest.lastUsed)) {
+			oldestID, oldest = id, b
+		}
+	}
+	if oldest != nil {
+		delete(cl.threads, oldestID)
+	}
+}
+
+// rateLimiter throttles outgoing traffic per account and per thread
+type rateLimiter struct {
+	mu         sync.Mutex
+	failFast   bool
+	maxWait    time.Duration
+	categories map[RateCategory]*rateCategoryLimiter
+}
+
+// newRateLimiter returns nil if rate limiting isn't configured or is disabled
+func newRateLimiter(cfg *RateLimitConfig) (*rateLimiter, error) {
+	if cfg == nil || cfg.Disabled {
+		return nil, nil
+	}
+	rl := &rateLimiter{
+		failFast:   cfg.FailFast,
+		maxWait:    time.Duration(cfg.MaxWaitMs) * time.Millisecond,
+		categories: make(map[RateCategory]*rateCategoryLimiter),
+	}
+	if rl.maxWait <= 0 {
+		rl.maxWait = defaultRateLimitMaxWait
+	}
+	configured := map[RateCategory]*RateBudget{
+		RateCategoryMessages:  cfg.Messages,
+		RateCategoryReactions: cfg.Reactions,
+		RateCategoryTyping:    cfg.Typing,
+	}
+	now := time.Now()
+	for category, budget := range configured {
+		if budget == nil {
+			def := defaultRateBudgets[category]
+			budget = &def
+		}
+		if budget.PerSecond <= 0 || budget.Burst < 1 || budget.ThreadPerSecond <= 0 || budget.ThreadBurst < 1 {
+			return nil, fmt.Errorf("invalid %s rate budget: rates must be positive and bursts at least 1", category)
+		}
+		rl.categories[category] = &rateCategoryLimiter{
+			budget:  *budget,
+			account: newTokenBucket(budget.PerSecond, budget.Burst, now),
+			threads: make(map[int64]*tokenBucket),
+		}
+	}
+	return rl, nil
+}
+
+// reserve takes a token from the account and thread buckets, returning how long
+// the caller must wait before using it. Nothing is taken if the call is rejected.
+func (rl *rateLimiter) reserve(category RateCategory, threadID int64) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	cl := rl.categories[category]
+	now := time.Now()
+
+	wait := cl.account.delay(now)
+	var thread *tokenBucket
+	if threadID != 0 {
+		thread = cl.threads[threadID]
+		if thread == nil {
+			if len(cl.threads) >= maxThreadBuckets {
+				cl.evictOldest(now)
+			}
+			thread = newTokenBucket(cl.budget.ThreadPerSecond, cl.budget.ThreadBurst, now)
+			cl.threads[threadID] = thread
+		}
+		wait = max(wait, thread.delay(now))
+	}
+
+	if wait > 0 && (rl.failFast || wait > rl.maxWait) {
+		return wait, false
+	}
+	cl.account.take(now)
+	if thread != nil {
+		thread.take(now)
+	}
+	return wait, true
+}
+
+// refund returns a reserved token the caller won't use, e.g. because it stopped waiting
+func (rl *rateLimiter) refund(category RateCategory, threadID int64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	cl := rl.categories[category]
+	now := time.Now()
+	cl.account.refund(now)
+	if thread := cl.threads[threadID]; thread != nil {
+		thread.refund(now)
+	}
+}
+
+// rateLimit waits for a send to fit in the budget of its category, or fails fast
+func (c *Client) rateLimit(category RateCategory, threadID int64) error {
+	if c.limiter == nil {
+		return nil
+	}
+	wait, ok := c.limiter.reserve(category, threadID)
+	if wait <= 0 {
+		return nil
+	}
+	c.emitEvent(EventTypeRateLimited, &RateLimitedEvent{
+		Category: category,
+		ThreadID: threadID,
+		WaitMs:   wait.Milliseconds(),
+		Rejected: !ok,
+	})
+	if !ok {
+		return fmt.Errorf("%w: %s budget exhausted, retry in %dms", ErrRateLimited, category, wait.Milliseconds())
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-c.ctx.Done():
+		c.limiter.refund(category, threadID)
+		return c.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rateLimitChat applies the rate limit to an E2EE chat, whose thread ID is the chat JID's user
+func (c *Client) rateLimitChat(category RateCategory, chatJID waTypes.JID) error {
+	threadID, _ := strconv.ParseInt(chatJID.User, 10, 64)
+	return c.rateLimit(category, threadID)
+}
+
+// rateLimitMessage applies the rate limit to a change of an existing message, in its thread if known
+func (c *Client) rateLimitMessage(category RateCategory, messageID string) error {
+	var threadID int64
+	if msg := c.state.getMessage(messageID); msg != nil {
+		threadID = msg.ThreadID
+	}
+	return c.rateLimit(category, threadID)
+}
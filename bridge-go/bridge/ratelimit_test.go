@@ -0,0 +1,112 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name      string
+		take      int           // tokens taken at start
+		elapsed   time.Duration // before checking
+		wantDelay time.Duration
+	}{
+		{"full", 0, 0, 0},
+		{"one left", 4, 0, 0},
+		{"empty", 5, 0, 500 * time.Millisecond},
+		{"refilled", 5, 500 * time.Millisecond, 0},
+		{"reserved ahead", 7, 0, 1500 * time.Millisecond},
+		{"reserved ahead, partly refilled", 7, time.Second, 500 * time.Millisecond},
+		{"capped at burst", 0, time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(2, 5, start)
+			for range tt.take {
+				b.take(start)
+			}
+			if got := b.delay(start.Add(tt.elapsed)); got != tt.wantDelay {
+				t.Fatalf("delay = %s, want %s", got, tt.wantDelay)
+			}
+			if b.tokens > b.burst {
+				t.Fatalf("tokens %f exceed burst %f", b.tokens, b.burst)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefund(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTokenBucket(1, 2, now)
+	b.take(now)
+	b.take(now)
+	b.take(now)
+	if !b.hasReservations(now) {
+		t.Fatal("expected a reservation after taking more than the burst")
+	}
+	b.refund(now)
+	if b.hasReservations(now) || b.delay(now) != time.Second {
+		t.Fatalf("after refund: tokens %f, delay %s", b.tokens, b.delay(now))
+	}
+	b.refund(now)
+	b.refund(now)
+	b.refund(now)
+	if b.tokens != b.burst {
+		t.Fatalf("refunds overfilled the bucket: %f", b.tokens)
+	}
+}
+
+func testRateLimiter(t *testing.T, failFast bool) *rateLimiter {
+	t.Helper()
+	budget := &RateBudget{PerSecond: 1000, Burst: 1000, ThreadPerSecond: 1, ThreadBurst: 1}
+	rl, err := newRateLimiter(&RateLimitConfig{FailFast: failFast, Messages: budget})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rl
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	rl := testRateLimiter(t, true)
+	if wait, ok := rl.reserve(RateCategoryMessages, 1); !ok || wait != 0 {
+		t.Fatalf("first reserve = %s, %v", wait, ok)
+	}
+	if wait, ok := rl.reserve(RateCategoryMessages, 1); ok || wait <= 0 {
+		t.Fatalf("second reserve in fail-fast mode = %s, %v, want rejected", wait, ok)
+	}
+	if wait, ok := rl.reserve(RateCategoryMessages, 2); !ok || wait != 0 {
+		t.Fatalf("reserve in another thread = %s, %v", wait, ok)
+	}
+
+	rl = testRateLimiter(t, false)
+	rl.reserve(RateCategoryMessages, 1)
+	if wait, ok := rl.reserve(RateCategoryMessages, 1); !ok || wait <= 0 {
+		t.Fatalf("waiting reserve = %s, %v", wait, ok)
+	}
+	rl.refund(RateCategoryMessages, 1)
+	if wait, _ := rl.reserve(RateCategoryMessages, 1); wait > time.Second {
+		t.Fatalf("reserve after refund waits %s, the refunded token wasn't returned", wait)
+	}
+}
+
+func TestRateLimiterEvictsOldestThreadBucket(t *testing.T) {
+	rl := testRateLimiter(t, false)
+	cl := rl.categories[RateCategoryMessages]
+	for id := int64(1); id <= maxThreadBuckets; id++ {
+		cl.threads[id] = newTokenBucket(1, 1, time.Now())
+	}
+	// Thread 1 is the least recently used but has a caller waiting, thread 2 is next
+	cl.threads[1].lastUsed = time.Unix(1, 0)
+	cl.threads[1].tokens = -5
+	cl.threads[2].lastUsed = time.Unix(2, 0)
+
+	rl.reserve(RateCategoryMessages, maxThreadBuckets+1)
+	if len(cl.threads) != maxThreadBuckets {
+		t.Fatalf("%d thread buckets, want %d", len(cl.threads), maxThreadBuckets)
+	}
+	if cl.threads[1] == nil || cl.threads[2] != nil || cl.threads[maxThreadBuckets+1] == nil {
+		t.Fatal("expected thread 2 to be evicted")
+	}
+}
@@ -0,0 +1,81 @@
+package bridge
+
+import (
+	"sync"
+	"time"
+)
+
+// autoMarkRead marks the threads of newly received regular messages as read, up
+// to the latest message from someone else in each thread. Receipts are sent on
+// receive, not once the app has handled the messages.
+func (c *Client) autoMarkRead(messages []*Message) {
+	if !c.autoRead {
+		return
+	}
+	watermarks := make(map[int64]int64)
+	for _, msg := range messages {
+		if msg.SenderID == c.FBID || msg.IsAdminMsg || msg.ThreadID == 0 {
+			continue
+		}
+		if msg.TimestampMs > watermarks[msg.ThreadID] {
+			watermarks[msg.ThreadID] = msg.TimestampMs
+		}
+	}
+	for threadID, watermarkMs := range watermarks {
+		// Marking waits for the server's response, which must not block event handling
+		go func(threadID, watermarkMs int64) {
+			if err := c.MarkRead(threadID, watermarkMs); err != nil {
+				c.Logger.Warn().Err(err).Int64("thread_id", threadID).Msg("Failed to auto-mark thread as read")
+			}
+		}(threadID, watermarkMs)
+	}
+}
+
+// e2eeReadBatchDelay is how long E2EE read receipts are collected before they're
+// sent, so a burst of messages gets one receipt per chat and sender
+const e2eeReadBatchDelay = time.Second
+
+// e2eeReadKey is a chat and sender whose messages are marked read together
+type e2eeReadKey struct {
+	chatJID   string
+	senderJID string
+}
+
+// e2eeReadBatcher collects received E2EE messages to mark read
+type e2eeReadBatcher struct {
+	mu      sync.Mutex
+	pending map[e2eeReadKey][]string // message IDs
+	timer   *time.Timer
+}
+
+// autoMarkE2EERead queues a read receipt for a newly received E2EE message
+func (c *Client) autoMarkE2EERead(msg *E2EEMessage) {
+	if !c.autoRead || msg.SenderID == c.FBID {
+		return
+	}
+	b := &c.e2eeReads
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending == nil {
+		b.pending = make(map[e2eeReadKey][]string)
+	}
+	key := e2eeReadKey{chatJID: msg.ChatJID, senderJID: msg.SenderJID}
+	b.pending[key] = append(b.pending[key], msg.ID)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(e2eeReadBatchDelay, c.flushE2EEReads)
+	}
+}
+
+// flushE2EEReads sends the queued E2EE read receipts
+func (c *Client) flushE2EEReads() {
+	b := &c.e2eeReads
+	b.mu.Lock()
+	pending := b.pending
+	b.pending, b.timer = nil, nil
+	b.mu.Unlock()
+	for key, messageIDs := range pending {
+		if err := c.MarkE2EERead(key.chatJID, messageIDs, key.senderJID); err != nil {
+			c.Logger.Warn().Err(err).Str("chat_jid", key.chatJID).Int("count", len(messageIDs)).Msg("Failed to auto-mark E2EE messages as read")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package bridge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutoMarkE2EEReadBatches(t *testing.T) {
+	c := &Client{FBID: 1, autoRead: true}
+	for _, msg := range []*E2EEMessage{
+		{ID: "a", ChatJID: "g@g.us", SenderJID: "2@msgr", SenderID: 2},
+		{ID: "b", ChatJID: "g@g.us", SenderJID: "3@msgr", SenderID: 3},
+		{ID: "c", ChatJID: "g@g.us", SenderJID: "2@msgr", SenderID: 2},
+		{ID: "d", ChatJID: "g@g.us", SenderJID: "1@msgr", SenderID: 1}, // Our own
+		{ID: "e", ChatJID: "2@msgr", SenderJID: "2@msgr", SenderID: 2},
+	} {
+		c.autoMarkE2EERead(msg)
+	}
+	c.e2eeReads.timer.Stop()
+
+	want := map[e2eeReadKey][]string{
+		{chatJID: "g@g.us", senderJID: "2@msgr"}: {"a", "c"},
+		{chatJID: "g@g.us", senderJID: "3@msgr"}: {"b"},
+		{chatJID: "2@msgr", senderJID: "2@msgr"}: {"e"},
+	}
+	if !reflect.DeepEqual(c.e2eeReads.pending, want) {
+		t.Fatalf("pending = %v, want %v", c.e2eeReads.pending, want)
+	}
+}
+
+func TestAutoMarkE2EEReadDisabled(t *testing.T) {
+	c := &Client{FBID: 1}
+	c.autoMarkE2EERead(&E2EEMessage{ID: "a", ChatJID: "2@msgr", SenderJID: "2@msgr", SenderID: 2})
+	if c.e2eeReads.pending != nil || c.e2eeReads.timer != nil {
+		t.Fatal("queued a receipt with autoMarkRead off")
+	}
+}
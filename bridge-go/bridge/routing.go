@@ -0,0 +1,225 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// ThreadRef addresses a thread by its thread key, its E2EE chat JID or both
+type ThreadRef struct {
+	ThreadID int64  `json:"threadId,omitempty"`
+	ChatJID  string `json:"chatJid,omitempty"`
+}
+
+// ResolvedThread is where sends to a ThreadRef go
+type ResolvedThread struct {
+	ThreadID int64  `json:"threadId"`
+	ChatJID  string `json:"chatJid,omitempty"` // Set for E2EE threads
+	IsE2EE   bool   `json:"isE2EE"`
+}
+
+// threadRegistry maps thread keys to the chat JIDs of E2EE threads. It's kept
+// in memory only, so after a restart a thread key routes to E2EE again only
+// once its chat has been seen in a sync or message, or registered again.
+type threadRegistry struct {
+	mu    sync.RWMutex
+	chats map[int64]string
+}
+
+func newThreadRegistry() *threadRegistry {
+	return &threadRegistry{chats: make(map[int64]string)}
+}
+
+func (r *threadRegistry) register(threadID int64, chatJID string) {
+	if threadID == 0 || chatJID == "" {
+		return
+	}
+	r.mu.Lock()
+	r.chats[threadID] = chatJID
+	r.mu.Unlock()
+}
+
+func (r *threadRegistry) lookup(threadID int64) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.chats[threadID]
+}
+
+// registerE2EEChat records the thread of an E2EE chat, whose key is the JID's user
+func (c *Client) registerE2EEChat(chatJID waTypes.JID) {
+	threadID, _ := strconv.ParseInt(chatJID.User, 10, 64)
+	c.threads.register(threadID, chatJID.String())
+}
+
+// RegisterE2EEChat tells the router that a thread is end-to-end encrypted.
+// Threads seen in syncs or E2EE messages are registered automatically.
+func (c *Client) RegisterE2EEChat(ref *ThreadRef) (*ResolvedThread, error) {
+	chatJID, err := waTypes.ParseJID(ref.ChatJID)
+	if err != nil || chatJID.IsEmpty() {
+		return nil, fmt.Errorf("invalid chatJid: %s", ref.ChatJID)
+	}
+	threadID := ref.ThreadID
+	if threadID == 0 {
+		threadID, _ = strconv.ParseInt(chatJID.User, 10, 64)
+	}
+	if threadID == 0 {
+		return nil, fmt.Errorf("chatJid %s has no thread key, set threadId", ref.ChatJID)
+	}
+	c.threads.register(threadID, chatJID.String())
+	return &ResolvedThread{ThreadID: threadID, ChatJID: chatJID.String(), IsE2EE: true}, nil
+}
+
+// ResolveThread finds where sends to a thread go. A chat JID always means E2EE,
+// a thread key is E2EE if the registry or the state mirror knows its chat JID.
+// Neither is persisted, so a thread key of an E2EE chat that hasn't been synced
+// since the last start resolves to a regular thread.
+func (c *Client) ResolveThread(ref *ThreadRef) (*ResolvedThread, error) {
+	if ref.ChatJID != "" {
+		chatJID, err := waTypes.ParseJID(ref.ChatJID)
+		if err != nil || chatJID.IsEmpty() {
+			return nil, fmt.Errorf("invalid chatJid: %s", ref.ChatJID)
+		}
+		threadID := ref.ThreadID
+		if threadID == 0 {
+			threadID, _ = strconv.ParseInt(chatJID.User, 10, 64)
+		}
+		return &ResolvedThread{ThreadID: threadID, ChatJID: chatJID.String(), IsE2EE: true}, nil
+	}
+	if ref.ThreadID == 0 {
+		return nil, fmt.Errorf("thread reference needs a threadId or chatJid")
+	}
+	chatJID := c.threads.lookup(ref.ThreadID)
+	if chatJID == "" {
+		if thread := c.state.getThread(ref.ThreadID); thread != nil {
+			chatJID = thread.E2EEChatJID
+		}
+	}
+	return &ResolvedThread{ThreadID: ref.ThreadID, ChatJID: chatJID, IsE2EE: chatJID != ""}, nil
+}
+
+// requireConnection fails loudly when the connection a thread needs is down
+func (c *Client) requireConnection(thread *ResolvedThread) error {
+	if thread.IsE2EE && !c.IsE2EEConnected() {
+		return fmt.Errorf("thread %d is end-to-end encrypted: %w", thread.ThreadID, ErrE2EENotConnected)
+	}
+	return nil
+}
+
+// routeMessage fills in the target of a send from its thread reference, thread
+// key or E2EE fields, so it goes to the right place whichever the caller set
+func (c *Client) routeMessage(opts *SendMessageOptions) (*ResolvedThread, error) {
+	ref := ThreadRef{ThreadID: opts.ThreadID, ChatJID: opts.E2EEChatJID}
+	if opts.Thread != nil {
+		ref = *opts.Thread
+	}
+	thread, err := c.ResolveThread(&ref)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IsE2EE && !thread.IsE2EE {
+		return nil, fmt.Errorf("thread %d has no known E2EE chat, set e2eeChatJid or register it", thread.ThreadID)
+	}
+	if thread.IsE2EE && (opts.StickerID != 0 || len(opts.AttachmentFbIds) > 0) {
+		return nil, fmt.Errorf("thread %d is end-to-end encrypted, send media with the SendE2EE functions", thread.ThreadID)
+	}
+	if err := c.requireConnection(thread); err != nil {
+		return nil, err
+	}
+	opts.Thread = nil
+	opts.ThreadID = thread.ThreadID
+	opts.E2EEChatJID = thread.ChatJID
+	opts.IsE2EE = thread.IsE2EE
+	return thread, nil
+}
+
+// routeUpload fails if media for a thread can't go through the regular upload,
+// so E2EE threads are rejected before anything is uploaded
+func (c *Client) routeUpload(threadID int64) error {
+	thread, err := c.ResolveThread(&ThreadRef{ThreadID: threadID})
+	if err != nil {
+		return err
+	}
+	if thread.IsE2EE {
+		return fmt.Errorf("thread %d is end-to-end encrypted, send media with the SendE2EE functions", thread.ThreadID)
+	}
+	return nil
+}
+
+// ReactOptions for reacting to a message in any thread
+type ReactOptions struct {
+	Thread         ThreadRef `json:"thread"`
+	MessageID      string    `json:"messageId"`
+	SenderJID      string    `json:"senderJid,omitempty"` // E2EE only, looked up from the state mirror if unset
+	Emoji          string    `json:"emoji"`               // Empty to remove
+	IdempotencyKey string    `json:"idempotencyKey,omitempty"`
+}
+
+// React sends a reaction, over E2EE if the thread is encrypted
+func (c *Client) React(opts *ReactOptions) error {
+	thread, err := c.ResolveThread(&opts.Thread)
+	if err != nil {
+		return err
+	}
+	if err := c.requireConnection(thread); err != nil {
+		return err
+	}
+	if !thread.IsE2EE {
+		return c.SendReaction(thread.ThreadID, opts.MessageID, opts.Emoji, opts.IdempotencyKey)
+	}
+	senderJID := opts.SenderJID
+	if senderJID == "" {
+		msg := c.state.getMessage(opts.MessageID)
+		if msg == nil || msg.SenderJID == "" {
+			return fmt.Errorf("senderJid is required, message %s isn't known", opts.MessageID)
+		}
+		senderJID = msg.SenderJID
+	}
+	return c.SendE2EEReaction(thread.ChatJID, opts.MessageID, senderJID, opts.Emoji, opts.IdempotencyKey)
+}
+
+// SetTypingOptions for typing indicators in any thread
+type SetTypingOptions struct {
+	Thread   ThreadRef `json:"thread"`
+	IsTyping bool      `json:"isTyping"`
+}
+
+// SetTyping sends a typing indicator, over E2EE if the thread is encrypted
+func (c *Client) SetTyping(opts *SetTypingOptions) error {
+	thread, err := c.ResolveThread(&opts.Thread)
+	if err != nil {
+		return err
+	}
+	if err := c.requireConnection(thread); err != nil {
+		return err
+	}
+	if thread.IsE2EE {
+		return c.SendE2EETyping(thread.ChatJID, opts.IsTyping)
+	}
+	return c.SendTypingIndicator(thread.ThreadID, opts.IsTyping, false, 0)
+}
+
+// MarkThreadReadOptions for marking any thread as read
+type MarkThreadReadOptions struct {
+	Thread      ThreadRef `json:"thread"`
+	WatermarkTs int64     `json:"watermarkTs,omitempty"` // Regular threads, defaults to now
+	MessageIDs  []string  `json:"messageIds,omitempty"`  // E2EE threads
+	SenderJID   string    `json:"senderJid,omitempty"`   // E2EE groups
+}
+
+// MarkThreadRead marks a thread as read, with receipts for the given messages if it's encrypted
+func (c *Client) MarkThreadRead(opts *MarkThreadReadOptions) error {
+	thread, err := c.ResolveThread(&opts.Thread)
+	if err != nil {
+		return err
+	}
+	if err := c.requireConnection(thread); err != nil {
+		return err
+	}
+	if thread.IsE2EE {
+		return c.MarkE2EERead(thread.ChatJID, opts.MessageIDs, opts.SenderJID)
+	}
+	return c.MarkRead(thread.ThreadID, opts.WatermarkTs)
+}
@@ -0,0 +1,33 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouteUpload(t *testing.T) {
+	c := &Client{state: newStateMirror(0), threads: newThreadRegistry()}
+	c.threads.register(100, "100@msgr.fb")
+
+	tests := []struct {
+		name     string
+		threadID int64
+		wantErr  string
+	}{
+		{"regular thread", 200, ""},
+		{"registered E2EE thread", 100, "end-to-end encrypted"},
+		{"no thread", 0, "needs a threadId"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.routeUpload(tt.threadID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
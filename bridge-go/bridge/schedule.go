@@ -0,0 +1,378 @@
+package bridge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScheduleStatus is the state of a scheduled message
+type ScheduleStatus string
+
+const (
+	ScheduleStatusScheduled ScheduleStatus = "scheduled"
+	ScheduleStatusSending   ScheduleStatus = "sending"
+	ScheduleStatusSent      ScheduleStatus = "sent"
+	ScheduleStatusFailed    ScheduleStatus = "failed"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+)
+
+const (
+	schedulePollInterval = 30 * time.Second // How often to recheck the connection while messages are due
+	scheduleRetention    = 7 * 24 * time.Hour
+)
+
+// ScheduledMessage is a message waiting to be sent at a set time. Sends that fail
+// with a transient error are retried with the outbox's backoff, other errors mark
+// the message failed at once.
+type ScheduledMessage struct {
+	ID              string             `json:"id"`
+	ThreadID        int64              `json:"threadId,omitempty"`
+	ChatJID         string             `json:"chatJid,omitempty"` // Set for E2EE chats
+	Content         SendMessageOptions `json:"content"`
+	SendAtMs        int64              `json:"sendAtMs"`
+	Status          ScheduleStatus     `json:"status"`
+	Attempts        int                `json:"attempts"`
+	Error           string             `json:"error,omitempty"`           // Error of the last attempt
+	NextAttemptAtMs int64              `json:"nextAttemptAtMs,omitempty"` // Set while waiting to retry
+	Result          *SendMessageResult `json:"result,omitempty"`
+	CreatedAtMs     int64              `json:"createdAtMs"`
+	UpdatedAtMs     int64              `json:"updatedAtMs"`
+}
+
+func (sm *ScheduledMessage) isFinished() bool {
+	return sm.Status == ScheduleStatusSent || sm.Status == ScheduleStatusFailed || sm.Status == ScheduleStatusCancelled
+}
+
+func (sm *ScheduledMessage) clone() *ScheduledMessage {
+	clone := *sm
+	return &clone
+}
+
+// scheduler holds scheduled messages, optionally persisted to a JSON file
+type scheduler struct {
+	mu       sync.Mutex
+	path     string // Empty for memory only
+	messages map[string]*ScheduledMessage
+	order    []string // IDs in scheduling order
+	wake     chan struct{}
+}
+
+func newScheduler(path string) (*scheduler, error) {
+	s := &scheduler{
+		path:     path,
+		messages: make(map[string]*ScheduledMessage),
+		wake:     make(chan struct{}, 1),
+	}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read scheduled messages: %w", err)
+	}
+	var messages []*ScheduledMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("corrupt scheduled messages: %w", err)
+	}
+	for _, sm := range messages {
+		// A send interrupted by a restart is sent again. Idempotency keys are only
+		// remembered in memory, so it's delivered twice if the first attempt went out.
+		if sm.Status == ScheduleStatusSending {
+			sm.Status = ScheduleStatusScheduled
+		}
+		s.messages[sm.ID] = sm
+		s.order = append(s.order, sm.ID)
+	}
+	return s, nil
+}
+
+func (s *scheduler) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	messages := make([]*ScheduledMessage, 0, len(s.order))
+	for _, id := range s.order {
+		messages = append(messages, s.messages[id])
+	}
+	if err := writeJSONFile(s.path, messages); err != nil {
+		return fmt.Errorf("failed to save scheduled messages: %w", err)
+	}
+	return nil
+}
+
+func (s *scheduler) pruneLocked(now time.Time) {
+	cutoff := now.Add(-scheduleRetention).UnixMilli()
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if sm := s.messages[id]; sm.isFinished() && sm.UpdatedAtMs < cutoff {
+			delete(s.messages, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) add(sm *ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked(time.Now())
+	s.messages[sm.ID] = sm
+	s.order = append(s.order, sm.ID)
+	if err := s.saveLocked(); err != nil {
+		delete(s.messages, sm.ID)
+		s.order = s.order[:len(s.order)-1]
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+func (s *scheduler) list(status ScheduleStatus) []*ScheduledMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := []*ScheduledMessage{}
+	for _, id := range s.order {
+		if sm := s.messages[id]; status == "" || sm.Status == status {
+			messages = append(messages, sm.clone())
+		}
+	}
+	return messages
+}
+
+// update changes a message that hasn't started sending yet
+func (s *scheduler) update(id string, change func(*ScheduledMessage)) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sm, ok := s.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown scheduled message: %s", id)
+	}
+	if sm.Status != ScheduleStatusScheduled {
+		return nil, fmt.Errorf("scheduled message %s is %s and can't be changed", id, sm.Status)
+	}
+	previous := *sm
+	change(sm)
+	sm.UpdatedAtMs = timeNowMs()
+	if err := s.saveLocked(); err != nil {
+		*sm = previous
+		return nil, err
+	}
+	s.notify()
+	return sm.clone(), nil
+}
+
+// next claims the earliest due message whose connection is up. If none is
+// due it returns how long to wait before checking again.
+func (s *scheduler) next(canSend func(*ScheduledMessage) bool) (*ScheduledMessage, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.pruneLocked(now)
+
+	wait := schedulePollInterval
+	var due *ScheduledMessage
+	for _, id := range s.order {
+		sm := s.messages[id]
+		if sm.Status != ScheduleStatusScheduled {
+			continue
+		}
+		if delay := time.UnixMilli(max(sm.SendAtMs, sm.NextAttemptAtMs)).Sub(now); delay > 0 {
+			wait = min(wait, delay)
+			continue
+		}
+		if canSend(sm) && (due == nil || sm.SendAtMs < due.SendAtMs) {
+			due = sm
+		}
+	}
+	if due == nil {
+		return nil, wait, nil
+	}
+	due.Status = ScheduleStatusSending
+	due.UpdatedAtMs = now.UnixMilli()
+	return due.clone(), 0, s.saveLocked()
+}
+
+// finish records the outcome of an attempt, scheduling a retry with backoff if the
+// failure is transient
+func (s *scheduler) finish(id string, result *SendMessageResult, sendErr error, transient bool) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sm, ok := s.messages[id]
+	if !ok {
+		return nil, nil
+	}
+	now := time.Now()
+	sm.Attempts++
+	sm.UpdatedAtMs = now.UnixMilli()
+	sm.NextAttemptAtMs = 0
+	if sendErr == nil {
+		sm.Status = ScheduleStatusSent
+		sm.Result = result
+		sm.Error = ""
+	} else {
+		sm.Error = sendErr.Error()
+		if !transient || sm.Attempts >= outboxMaxAttempts {
+			sm.Status = ScheduleStatusFailed
+		} else {
+			sm.Status = ScheduleStatusScheduled
+			sm.NextAttemptAtMs = now.Add(outboxBackoff(sm.Attempts)).UnixMilli()
+		}
+	}
+	return sm.clone(), s.saveLocked()
+}
+
+// ScheduleMessageOptions for scheduling a message
+type ScheduleMessageOptions struct {
+	ThreadID int64              `json:"threadId,omitempty"`
+	ChatJID  string             `json:"chatJid,omitempty"` // E2EE chat, instead of threadId
+	Content  SendMessageOptions `json:"content"`           // Text, reply and mentions; the target fields are ignored
+	SendAtMs int64              `json:"sendAtMs"`
+}
+
+// ScheduleMessage schedules a message to be sent at a set time. Messages due
+// while disconnected are sent as soon as the connection is back. Delivery is at
+// least once: a send interrupted by a restart is sent again.
+func (c *Client) ScheduleMessage(opts *ScheduleMessageOptions) (*ScheduledMessage, error) {
+	if (opts.ThreadID == 0) == (opts.ChatJID == "") {
+		return nil, fmt.Errorf("exactly one of threadId and chatJid is required")
+	}
+	if opts.ChatJID != "" {
+		if _, err := parseJID(opts.ChatJID); err != nil {
+			return nil, fmt.Errorf("invalid chatJid: %w", err)
+		}
+	}
+	if opts.ChatJID != "" && opts.Content.Text == "" {
+		return nil, fmt.Errorf("scheduled E2EE messages need text")
+	}
+	if opts.Content.Text == "" && opts.Content.StickerID == 0 && len(opts.Content.AttachmentFbIds) == 0 {
+		return nil, fmt.Errorf("content is empty")
+	}
+	if opts.SendAtMs <= timeNowMs() {
+		return nil, fmt.Errorf("sendAtMs must be in the future")
+	}
+
+	id := strconv.FormatInt(c.ids.next(), 10)
+	content := opts.Content
+	content.ThreadID = opts.ThreadID
+	content.IsE2EE = opts.ChatJID != ""
+	content.E2EEChatJID = opts.ChatJID
+	if err := content.resolveMentions(); err != nil {
+		return nil, err
+	}
+	if content.IdempotencyKey == "" {
+		content.IdempotencyKey = "scheduled:" + id
+	}
+
+	now := timeNowMs()
+	sm := &ScheduledMessage{
+		ID:          id,
+		ThreadID:    opts.ThreadID,
+		ChatJID:     opts.ChatJID,
+		Content:     content,
+		SendAtMs:    opts.SendAtMs,
+		Status:      ScheduleStatusScheduled,
+		CreatedAtMs: now,
+		UpdatedAtMs: now,
+	}
+	if err := c.scheduler.add(sm); err != nil {
+		return nil, err
+	}
+	return sm.clone(), nil
+}
+
+// ListScheduledMessages returns scheduled messages, optionally only those with a status
+func (c *Client) ListScheduledMessages(status ScheduleStatus) []*ScheduledMessage {
+	return c.scheduler.list(status)
+}
+
+// CancelScheduledMessage cancels a message that hasn't been sent yet
+func (c *Client) CancelScheduledMessage(id string) (*ScheduledMessage, error) {
+	return c.scheduler.update(id, func(sm *ScheduledMessage) {
+		sm.Status = ScheduleStatusCancelled
+	})
+}
+
+// RescheduleMessageOptions for moving a scheduled message to another time
+type RescheduleMessageOptions struct {
+	ID       string `json:"id"`
+	SendAtMs int64  `json:"sendAtMs"`
+}
+
+// RescheduleMessage changes when a message that hasn't been sent yet goes out
+func (c *Client) RescheduleMessage(opts *RescheduleMessageOptions) (*ScheduledMessage, error) {
+	if opts.SendAtMs <= timeNowMs() {
+		return nil, fmt.Errorf("sendAtMs must be in the future")
+	}
+	return c.scheduler.update(opts.ID, func(sm *ScheduledMessage) {
+		sm.SendAtMs = opts.SendAtMs
+		sm.Attempts = 0
+		sm.NextAttemptAtMs = 0
+	})
+}
+
+// runScheduler sends scheduled messages when they're due, until the client is closed
+func (c *Client) runScheduler() {
+	canSend := func(sm *ScheduledMessage) bool {
+		thread, err := c.ResolveThread(&ThreadRef{ThreadID: sm.ThreadID, ChatJID: sm.ChatJID})
+		// An unresolvable thread is sent anyway, so the failure is reported
+		return err != nil || c.canSend(thread.IsE2EE)
+	}
+	for {
+		sm, wait, err := c.scheduler.next(canSend)
+		if err != nil {
+			c.Logger.Warn().Err(err).Msg("Failed to save scheduled messages")
+		}
+		if sm != nil {
+			c.sendScheduledMessage(sm)
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-c.scheduler.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (c *Client) sendScheduledMessage(sm *ScheduledMessage) {
+	content := sm.Content
+	result, sendErr := c.SendMessage(&content)
+
+	transient := sendErr != nil && c.isTransientSendError(sendErr, sm.ChatJID != "")
+	updated, err := c.scheduler.finish(sm.ID, result, sendErr, transient)
+	if err != nil {
+		c.Logger.Warn().Err(err).Msg("Failed to save scheduled messages")
+	}
+	if updated == nil {
+		return
+	}
+	if updated.NextAttemptAtMs != 0 {
+		c.Logger.Warn().Err(sendErr).Str("id", sm.ID).Int("attempts", updated.Attempts).Msg("Scheduled message send failed, retrying")
+	}
+	switch updated.Status {
+	case ScheduleStatusSent:
+		c.emitEvent(EventTypeScheduledMessageSent, updated)
+	case ScheduleStatusFailed:
+		c.emitEvent(EventTypeScheduledMessageFailed, updated)
+	}
+}
@@ -0,0 +1,48 @@
+package bridge
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSchedulerFinish(t *testing.T) {
+	errSend := errors.New("send failed")
+	tests := []struct {
+		name       string
+		attempts   int // before this one
+		sendErr    error
+		transient  bool
+		wantStatus ScheduleStatus
+		wantRetry  bool
+	}{
+		{"sent", 0, nil, false, ScheduleStatusSent, false},
+		{"permanent error", 0, errSend, false, ScheduleStatusFailed, false},
+		{"transient error", 0, errSend, true, ScheduleStatusScheduled, true},
+		{"transient error on the last attempt", outboxMaxAttempts - 1, errSend, true, ScheduleStatusFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newScheduler("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sm := &ScheduledMessage{ID: "1", ThreadID: 1, Status: ScheduleStatusSending, Attempts: tt.attempts}
+			if err := s.add(sm); err != nil {
+				t.Fatal(err)
+			}
+			updated, err := s.finish("1", &SendMessageResult{MessageID: "mid.1"}, tt.sendErr, tt.transient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != tt.wantStatus || updated.Attempts != tt.attempts+1 {
+				t.Fatalf("status %s after %d attempts, want %s after %d", updated.Status, updated.Attempts, tt.wantStatus, tt.attempts+1)
+			}
+			if (updated.NextAttemptAtMs != 0) != tt.wantRetry {
+				t.Fatalf("nextAttemptAtMs = %d, want retry %v", updated.NextAttemptAtMs, tt.wantRetry)
+			}
+			if (updated.Result != nil) != (tt.sendErr == nil) {
+				t.Fatalf("result = %+v", updated.Result)
+			}
+		})
+	}
+}
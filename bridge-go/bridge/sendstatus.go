@@ -0,0 +1,286 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/socket"
+	"go.mau.fi/mautrix-meta/pkg/messagix/table"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waConsumerApplication"
+	"go.mau.fi/whatsmeow/proto/waMsgApplication"
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// SendStatus is the delivery state of a message we sent
+type SendStatus string
+
+const (
+	SendStatusPending   SendStatus = "pending"
+	SendStatusSent      SendStatus = "sent" // Accepted by the server
+	SendStatusDelivered SendStatus = "delivered"
+	SendStatusRead      SendStatus = "read"
+	SendStatusFailed    SendStatus = "failed"
+)
+
+// sendStatusRank orders the non-failed states, a send only moves forward
+var sendStatusRank = map[SendStatus]int{
+	SendStatusPending:   0,
+	SendStatusSent:      1,
+	SendStatusDelivered: 2,
+	SendStatusRead:      3,
+}
+
+// maxTrackedSends is how many recent sends the tracker remembers
+const maxTrackedSends = 1000
+
+// MessageStatus is the tracked state of a sent message, and the data of messageStatus events.
+// In groups, delivered and read mean at least one other participant received or read it.
+type MessageStatus struct {
+	TrackingID  string     `json:"trackingId"`          // The otid for regular sends, the message ID for E2EE sends
+	MessageID   string     `json:"messageId,omitempty"` // Empty until the server assigns one
+	ThreadID    int64      `json:"threadId"`
+	ChatJID     string     `json:"chatJid,omitempty"` // E2EE only
+	IsE2EE      bool       `json:"isE2EE,omitempty"`
+	Status      SendStatus `json:"status"`
+	Reason      string     `json:"reason,omitempty"` // Failure reason
+	SentAtMs    int64      `json:"sentAtMs"`         // Local time of the send
+	UpdatedAtMs int64      `json:"updatedAtMs"`
+	// Server time of the message once it's known, receipt watermarks are compared with it
+	ServerTimestampMs int64 `json:"serverTimestampMs,omitempty"`
+}
+
+// sendTracker follows recent sends from pending to their final state
+type sendTracker struct {
+	mu          sync.Mutex
+	sends       map[string]*MessageStatus // by tracking ID
+	byMessageID map[string]*MessageStatus
+	order       []string // tracking IDs, oldest first
+}
+
+func newSendTracker() *sendTracker {
+	return &sendTracker{
+		sends:       make(map[string]*MessageStatus),
+		byMessageID: make(map[string]*MessageStatus),
+	}
+}
+
+func (t *sendTracker) track(status *MessageStatus) *MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status.Status = SendStatusPending
+	status.SentAtMs = timeNowMs()
+	status.UpdatedAtMs = status.SentAtMs
+	if _, ok := t.sends[status.TrackingID]; !ok {
+		t.order = append(t.order, status.TrackingID)
+	}
+	t.sends[status.TrackingID] = status
+	if len(t.order) > maxTrackedSends {
+		for _, id := range t.order[:len(t.order)-maxTrackedSends] {
+			if old, ok := t.sends[id]; ok {
+				delete(t.byMessageID, old.MessageID)
+				delete(t.sends, id)
+			}
+		}
+		t.order = append([]string(nil), t.order[len(t.order)-maxTrackedSends:]...)
+	}
+	clone := *status
+	return &clone
+}
+
+// advanceLocked moves a send to a new state, returning a copy if it changed
+func (t *sendTracker) advanceLocked(status *MessageStatus, to SendStatus, messageID, reason string) *MessageStatus {
+	if status.Status == SendStatusFailed {
+		return nil
+	}
+	changed := false
+	if messageID != "" && status.MessageID != messageID {
+		delete(t.byMessageID, status.MessageID)
+		status.MessageID = messageID
+		t.byMessageID[messageID] = status
+		changed = true
+	}
+	if to == SendStatusFailed {
+		status.Status = SendStatusFailed
+		status.Reason = reason
+		changed = true
+	} else if sendStatusRank[to] > sendStatusRank[status.Status] {
+		status.Status = to
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	status.UpdatedAtMs = timeNowMs()
+	clone := *status
+	return &clone
+}
+
+func (t *sendTracker) advance(trackingID string, to SendStatus, messageID, reason string) *MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if status, ok := t.sends[trackingID]; ok {
+		return t.advanceLocked(status, to, messageID, reason)
+	}
+	return nil
+}
+
+// markSent moves a regular send to sent, storing the server timestamp of the message if known
+func (t *sendTracker) markSent(trackingID, messageID string, serverTimestampMs int64) *MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status, ok := t.sends[trackingID]
+	if !ok {
+		return nil
+	}
+	if serverTimestampMs > 0 && status.ServerTimestampMs == 0 && status.Status != SendStatusFailed {
+		status.ServerTimestampMs = serverTimestampMs
+	}
+	return t.advanceLocked(status, SendStatusSent, messageID, "")
+}
+
+// setServerTimestamps stores the server timestamps of sent messages that didn't have one yet,
+// e.g. when the message row arrives after the send was acknowledged
+func (t *sendTracker) setServerTimestamps(timestamps map[string]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for messageID, timestampMs := range timestamps {
+		if status, ok := t.byMessageID[messageID]; ok && status.ServerTimestampMs == 0 && timestampMs > 0 {
+			status.ServerTimestampMs = timestampMs
+		}
+	}
+}
+
+func (t *sendTracker) advanceMessages(messageIDs []string, to SendStatus) []*MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changed []*MessageStatus
+	for _, id := range messageIDs {
+		if status, ok := t.byMessageID[id]; ok {
+			if update := t.advanceLocked(status, to, "", ""); update != nil {
+				changed = append(changed, update)
+			}
+		}
+	}
+	return changed
+}
+
+// advanceThread moves regular sends in a thread up to a receipt watermark. Watermarks
+// are server times, so sends whose server timestamp isn't known yet are left alone.
+func (t *sendTracker) advanceThread(threadID, watermarkMs int64, to SendStatus) []*MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changed []*MessageStatus
+	for _, id := range t.order {
+		status, ok := t.sends[id]
+		if !ok || status.IsE2EE || status.ThreadID != threadID || status.ServerTimestampMs == 0 ||
+			status.ServerTimestampMs > watermarkMs || status.Status == SendStatusPending {
+			continue
+		}
+		if update := t.advanceLocked(status, to, "", ""); update != nil {
+			changed = append(changed, update)
+		}
+	}
+	return changed
+}
+
+func (t *sendTracker) get(trackingID string) *MessageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if status, ok := t.sends[trackingID]; ok {
+		clone := *status
+		return &clone
+	}
+	return nil
+}
+
+func (c *Client) emitMessageStatus(statuses ...*MessageStatus) {
+	for _, status := range statuses {
+		if status != nil {
+			c.emitEvent(EventTypeMessageStatus, status)
+		}
+	}
+}
+
+// GetMessageStatus returns the delivery state of a recent send
+func (c *Client) GetMessageStatus(trackingID string) (*MessageStatus, error) {
+	if status := c.sends.get(trackingID); status != nil {
+		return status, nil
+	}
+	return nil, fmt.Errorf("unknown tracking ID: %s", trackingID)
+}
+
+// executeTrackedSend runs a send task and tracks the message by its otid. The
+// returned message ID is a placeholder if the response doesn't include the real
+// one yet, the messageStatus event for "sent" carries it once known.
+func (c *Client) executeTrackedSend(task socket.Task, threadID, otid int64) (*SendMessageResult, error) {
+	if err := c.rateLimit(RateCategoryMessages, threadID); err != nil {
+		return nil, err
+	}
+	trackingID := strconv.FormatInt(otid, 10)
+	c.emitMessageStatus(c.sends.track(&MessageStatus{TrackingID: trackingID, ThreadID: threadID}))
+
+	resp, err := c.Messagix.ExecuteTasks(c.ctx, task)
+	if err != nil {
+		c.emitMessageStatus(c.sends.advance(trackingID, SendStatusFailed, "", err.Error()))
+		return nil, err
+	}
+
+	result := &SendMessageResult{
+		MessageID:   generateMID(otid),
+		TrackingID:  trackingID,
+		TimestampMs: timeNowMs(),
+	}
+	if resp != nil {
+		for _, f := range resp.LSMarkOptimisticMessageFailed {
+			if f.Otid == trackingID {
+				c.emitMessageStatus(c.sends.advance(trackingID, SendStatusFailed, "", f.Message))
+				return nil, fmt.Errorf("send failed: %s", f.Message)
+			}
+		}
+		// Try to get actual message ID from response
+		for _, r := range resp.LSReplaceOptimsiticMessage {
+			if r.OfflineThreadingId == trackingID {
+				result.MessageID = r.MessageId
+				c.emitMessageStatus(c.sends.markSent(trackingID, r.MessageId, messageTimestamps(resp)[r.MessageId]))
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// messageTimestamps returns the server timestamps of the message rows in a table by message ID
+func messageTimestamps(tbl *table.LSTable) map[string]int64 {
+	timestamps := make(map[string]int64, len(tbl.LSInsertMessage)+len(tbl.LSUpsertMessage))
+	for _, m := range tbl.LSInsertMessage {
+		timestamps[m.MessageId] = m.TimestampMs
+	}
+	for _, m := range tbl.LSUpsertMessage {
+		timestamps[m.MessageId] = m.TimestampMs
+	}
+	return timestamps
+}
+
+// sendTrackedE2EE sends an E2EE message and tracks it by its message ID
+func (c *Client) sendTrackedE2EE(chatJID waTypes.JID, msg *waConsumerApplication.ConsumerApplication, metadata *waMsgApplication.MessageApplication_Metadata, extra whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	if err := c.rateLimitChat(RateCategoryMessages, chatJID); err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+	threadID, _ := strconv.ParseInt(chatJID.User, 10, 64)
+	c.emitMessageStatus(c.sends.track(&MessageStatus{
+		TrackingID: extra.ID,
+		ThreadID:   threadID,
+		ChatJID:    chatJID.String(),
+		IsE2EE:     true,
+	}))
+
+	resp, err := c.E2EE.SendFBMessage(c.ctx, chatJID, msg, metadata, extra)
+	if err != nil {
+		c.emitMessageStatus(c.sends.advance(extra.ID, SendStatusFailed, "", err.Error()))
+		return resp, err
+	}
+	c.emitMessageStatus(c.sends.advance(extra.ID, SendStatusSent, extra.ID, ""))
+	return resp, nil
+}
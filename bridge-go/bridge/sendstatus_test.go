@@ -0,0 +1,49 @@
+package bridge
+
+import "testing"
+
+func TestSendTrackerAdvanceThread(t *testing.T) {
+	tests := []struct {
+		name        string
+		serverTime  int64 // 0 if the server timestamp never arrived
+		watermarkMs int64
+		want        SendStatus
+	}{
+		{"before watermark", 1000, 2000, SendStatusDelivered},
+		{"at watermark", 2000, 2000, SendStatusDelivered},
+		{"after watermark", 3000, 2000, SendStatusSent},
+		{"unknown server time", 0, 2000, SendStatusSent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newSendTracker()
+			tracker.track(&MessageStatus{TrackingID: "1", ThreadID: 5})
+			tracker.markSent("1", "mid.1", tt.serverTime)
+			tracker.advanceThread(5, tt.watermarkMs, SendStatusDelivered)
+			if got := tracker.get("1"); got.Status != tt.want {
+				t.Fatalf("status = %s, want %s", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendTrackerLateServerTimestamp(t *testing.T) {
+	tracker := newSendTracker()
+	tracker.track(&MessageStatus{TrackingID: "1", ThreadID: 5})
+	tracker.markSent("1", "mid.1", 0)
+	tracker.setServerTimestamps(map[string]int64{"mid.1": 1000, "mid.other": 500})
+	if changed := tracker.advanceThread(5, 1500, SendStatusRead); len(changed) != 1 || changed[0].Status != SendStatusRead {
+		t.Fatalf("advanceThread = %+v, want one read status", changed)
+	}
+	if got := tracker.get("1"); got.ServerTimestampMs != 1000 || got.MessageID != "mid.1" {
+		t.Fatalf("status = %+v", got)
+	}
+}
+
+func TestSendTrackerPendingNotAdvanced(t *testing.T) {
+	tracker := newSendTracker()
+	tracker.track(&MessageStatus{TrackingID: "1", ThreadID: 5})
+	if changed := tracker.advanceThread(5, timeNowMs()+1000, SendStatusRead); len(changed) != 0 {
+		t.Fatalf("pending send advanced: %+v", changed)
+	}
+}
@@ -0,0 +1,85 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	waTypes "go.mau.fi/whatsmeow/types"
+)
+
+// SessionResetResult describes the E2EE state removed for a contact
+type SessionResetResult struct {
+	UserJID    string   `json:"userJid"`
+	Sessions   []string `json:"sessions"`   // Signal addresses whose sessions were deleted
+	Identities []string `json:"identities"` // Signal addresses whose identity keys were forgotten
+	SenderKeys int      `json:"senderKeys"` // group sender keys received from the contact
+}
+
+// isUserSignalAddress checks if a Signal address ("user[_agent]:device") belongs to user
+func isUserSignalAddress(address, user string) bool {
+	return strings.HasPrefix(address, user+":") || strings.HasPrefix(address, user+"_")
+}
+
+// ResetUser removes all sessions, identities and received sender keys for every device of a user
+func (ds *DeviceStore) ResetUser(user string) *SessionResetResult {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	result := &SessionResetResult{
+		Sessions:   []string{},
+		Identities: []string{},
+	}
+	for address := range ds.sessions {
+		if isUserSignalAddress(address, user) {
+			delete(ds.sessions, address)
+			result.Sessions = append(result.Sessions, address)
+		}
+	}
+	for address := range ds.identities {
+		if isUserSignalAddress(address, user) {
+			delete(ds.identities, address)
+			result.Identities = append(result.Identities, address)
+		}
+	}
+	for address := range ds.pendingIdentities {
+		if isUserSignalAddress(address, user) {
+			delete(ds.pendingIdentities, address)
+		}
+	}
+	// Sender key entries are keyed by "group:senderAddress"
+	for key := range ds.senderKeys {
+		if idx := strings.IndexByte(key, ':'); idx >= 0 && isUserSignalAddress(key[idx+1:], user) {
+			delete(ds.senderKeys, key)
+			result.SenderKeys++
+		}
+	}
+	sort.Strings(result.Sessions)
+	sort.Strings(result.Identities)
+	go ds.Save()
+	return result
+}
+
+// ResetE2EESession drops all E2EE state for a contact so the next send fetches fresh prekeys
+// and establishes new sessions. Accepts a user JID or a 1:1 chat JID.
+func (c *Client) ResetE2EESession(jidStr string) (*SessionResetResult, error) {
+	jid, err := parseJID(jidStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jid: %w", err)
+	}
+	if jid.IsEmpty() {
+		return nil, fmt.Errorf("jid is required")
+	}
+	if jid.Server == waTypes.GroupServer {
+		return nil, fmt.Errorf("cannot reset a group chat, pass a participant's user JID")
+	}
+
+	result := c.DeviceStore.ResetUser(jid.User)
+	result.UserJID = jid.ToNonAD().String()
+	c.Logger.Info().
+		Str("user", result.UserJID).
+		Int("sessions", len(result.Sessions)).
+		Int("identities", len(result.Identities)).
+		Int("senderKeys", result.SenderKeys).
+		Msg("Reset E2EE session")
+	return result, nil
+}
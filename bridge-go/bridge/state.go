@@ -0,0 +1,406 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/table"
+)
+
+// defaultStateMessagesPerThread is how many recent messages the state mirror keeps per thread
+const defaultStateMessagesPerThread = 100
+
+// StoredMessage is a regular or E2EE message kept in the state mirror
+type StoredMessage struct {
+	ID          string        `json:"id"`
+	ThreadID    int64         `json:"threadId"`
+	ChatJID     string        `json:"chatJid,omitempty"` // E2EE only
+	SenderID    int64         `json:"senderId"`
+	SenderJID   string        `json:"senderJid,omitempty"` // E2EE only
+	Text        string        `json:"text"`
+	TimestampMs int64         `json:"timestampMs"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	ReplyTo     *ReplyTo      `json:"replyTo,omitempty"`
+	Mentions    []*Mention    `json:"mentions,omitempty"`
+	IsE2EE      bool          `json:"isE2EE,omitempty"`
+	IsAdminMsg  bool          `json:"isAdminMsg,omitempty"`
+	EditCount   int64         `json:"editCount,omitempty"`
+	IsUnsent    bool          `json:"isUnsent,omitempty"`
+}
+
+func storedFromMessage(m *Message) *StoredMessage {
+	return &StoredMessage{
+		ID:          m.ID,
+		ThreadID:    m.ThreadID,
+		SenderID:    m.SenderID,
+		Text:        m.Text,
+		TimestampMs: m.TimestampMs,
+		Attachments: m.Attachments,
+		ReplyTo:     m.ReplyTo,
+		Mentions:    m.Mentions,
+		IsAdminMsg:  m.IsAdminMsg,
+	}
+}
+
+func storedFromE2EEMessage(m *E2EEMessage) *StoredMessage {
+	return &StoredMessage{
+		ID:          m.ID,
+		ThreadID:    m.ThreadID,
+		ChatJID:     m.ChatJID,
+		SenderID:    m.SenderID,
+		SenderJID:   m.SenderJID,
+		Text:        m.Text,
+		TimestampMs: m.TimestampMs,
+		Attachments: m.Attachments,
+		ReplyTo:     m.ReplyTo,
+		Mentions:    m.Mentions,
+		IsE2EE:      true,
+	}
+}
+
+// stateMirror is an in-memory view of threads, contacts and recent messages,
+// kept up to date from LightSpeed tables and E2EE events
+type stateMirror struct {
+	mu                sync.RWMutex
+	messagesPerThread int
+	threads           map[int64]*Thread
+	contacts          map[int64]*ContactInfo
+	messages          map[int64][]*StoredMessage // per thread, oldest first
+	messageIndex      map[string]*StoredMessage
+	polls             map[int64]*Poll
+}
+
+func newStateMirror(messagesPerThread int) *stateMirror {
+	if messagesPerThread <= 0 {
+		messagesPerThread = defaultStateMessagesPerThread
+	}
+	return &stateMirror{
+		messagesPerThread: messagesPerThread,
+		threads:           make(map[int64]*Thread),
+		contacts:          make(map[int64]*ContactInfo),
+		messages:          make(map[int64][]*StoredMessage),
+		messageIndex:      make(map[string]*StoredMessage),
+		polls:             make(map[int64]*Poll),
+	}
+}
+
+// applyTable updates the mirror from a table and the threads and messages converted from it,
+// and returns the changes to threads that were already known
+func (s *stateMirror) applyTable(tbl *table.LSTable, threads []*Thread, messages []*Message) []*ThreadUpdateEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updates []*ThreadUpdateEvent
+	for _, thread := range threads {
+		if existing, ok := s.threads[thread.ID]; ok {
+			// Participant rows in the batch are applied to the known list below
+			thread.Participants = existing.Participants
+			if thread.MemberCount == 0 {
+				thread.MemberCount = existing.MemberCount
+			}
+			updates = append(updates, diffThread(existing, thread)...)
+		}
+		s.threads[thread.ID] = cloneThread(thread)
+	}
+
+	// Row updates, which were already applied to new threads in the batch
+	added := make(map[int64]*ThreadUpdateEvent)
+	removed := make(map[int64]*ThreadUpdateEvent)
+	for _, p := range tbl.LSAddParticipantIdToGroupThread {
+		thread := s.threads[p.ThreadKey]
+		if thread == nil {
+			continue
+		}
+		participant := &ThreadParticipant{
+			UserID:                        p.ContactId,
+			Nickname:                      p.Nickname,
+			IsAdmin:                       p.IsAdmin,
+			IsSuperAdmin:                  p.IsSuperAdmin,
+			ReadWatermarkTimestampMs:      p.ReadWatermarkTimestampMs,
+			DeliveredWatermarkTimestampMs: p.DeliveredWatermarkTimestampMs,
+		}
+		if existing := findParticipant(thread.Participants, p.ContactId); existing != nil {
+			updates = append(updates, diffParticipant(thread.ID, existing, participant)...)
+		} else if update := added[thread.ID]; update != nil {
+			update.UserIDs = append(update.UserIDs, p.ContactId)
+		} else {
+			added[thread.ID] = &ThreadUpdateEvent{ThreadID: thread.ID, Kind: ThreadUpdateParticipantsAdded, UserIDs: []int64{p.ContactId}}
+			updates = append(updates, added[thread.ID])
+		}
+		thread.Participants = append(removeParticipant(thread.Participants, p.ContactId), participant)
+	}
+	for _, p := range tbl.LSRemoveParticipantFromThread {
+		thread := s.threads[p.ThreadKey]
+		if thread == nil || findParticipant(thread.Participants, p.ParticipantId) == nil {
+			continue
+		}
+		if update := removed[thread.ID]; update != nil {
+			update.UserIDs = append(update.UserIDs, p.ParticipantId)
+		} else {
+			removed[thread.ID] = &ThreadUpdateEvent{ThreadID: thread.ID, Kind: ThreadUpdateParticipantsRemoved, UserIDs: []int64{p.ParticipantId}}
+			updates = append(updates, removed[thread.ID])
+		}
+		thread.Participants = removeParticipant(thread.Participants, p.ParticipantId)
+	}
+	for _, u := range tbl.LSSyncUpdateThreadName {
+		if thread := s.threads[u.ThreadKey]; thread != nil && thread.Name != u.ThreadName {
+			updates = append(updates, &ThreadUpdateEvent{ThreadID: thread.ID, Kind: ThreadUpdateRename, Before: thread.Name, After: u.ThreadName})
+			thread.Name = u.ThreadName
+		}
+	}
+	for _, u := range tbl.LSSetThreadImageURL {
+		if thread := s.threads[u.ThreadKey]; thread != nil && thread.PictureURL != u.ImageURL {
+			if !samePicture(thread.PictureURL, u.ImageURL) {
+				updates = append(updates, &ThreadUpdateEvent{ThreadID: thread.ID, Kind: ThreadUpdatePhotoChanged, Before: thread.PictureURL, After: u.ImageURL})
+			}
+			thread.PictureURL = u.ImageURL
+		}
+	}
+	for _, u := range tbl.LSUpdateThreadMuteSetting {
+		if thread := s.threads[u.ThreadKey]; thread != nil {
+			thread.MutedUntilMs = u.MuteExpireTimeMS
+		}
+	}
+	for _, r := range tbl.LSMarkThreadReadV2 {
+		if thread := s.threads[r.ThreadKey]; thread != nil {
+			thread.LastReadWatermarkTimestampMs = r.LastReadWatermarkTimestampMs
+			thread.IsUnread = thread.LastActivityTimestampMs > r.LastReadWatermarkTimestampMs
+			thread.UnreadCount = 0
+		}
+	}
+
+	for _, contact := range tbl.LSDeleteThenInsertContact {
+		s.contacts[contact.Id] = &ContactInfo{
+			ID:                contact.Id,
+			Name:              contact.Name,
+			FirstName:         contact.FirstName,
+			Username:          contact.Username,
+			ProfilePictureUrl: contact.GetAvatarURL(),
+			IsMessengerUser:   contact.IsMessengerUser,
+			Gender:            int64(contact.Gender),
+			CanViewerMessage:  contact.CanViewerMessage,
+		}
+	}
+	for _, contact := range tbl.LSVerifyContactRowExists {
+		if _, ok := s.contacts[contact.ContactId]; ok {
+			continue
+		}
+		s.contacts[contact.ContactId] = &ContactInfo{
+			ID:                contact.ContactId,
+			Name:              contact.Name,
+			FirstName:         contact.FirstName,
+			Username:          contact.Username,
+			ProfilePictureUrl: contact.GetAvatarURL(),
+			IsMessengerUser:   contact.IsMessengerUser,
+		}
+	}
+
+	// Edits and unsends are applied by the event handlers, which need the previous content
+	for _, m := range messages {
+		s.putMessageLocked(storedFromMessage(m))
+	}
+	return updates
+}
+
+// putMessage adds or replaces a message
+func (s *stateMirror) putMessage(msg *StoredMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putMessageLocked(msg)
+}
+
+func (s *stateMirror) putMessageLocked(msg *StoredMessage) {
+	if msg.ID == "" {
+		return
+	}
+	if existing, ok := s.messageIndex[msg.ID]; ok {
+		// Keep local edit/unsend state when a message is upserted again
+		if msg.EditCount < existing.EditCount {
+			msg.EditCount = existing.EditCount
+		}
+		msg.IsUnsent = msg.IsUnsent || existing.IsUnsent
+		*existing = *msg
+		return
+	}
+
+	list := s.messages[msg.ThreadID]
+	idx := sort.Search(len(list), func(i int) bool { return list[i].TimestampMs > msg.TimestampMs })
+	list = append(list, nil)
+	copy(list[idx+1:], list[idx:])
+	list[idx] = msg
+	s.messageIndex[msg.ID] = msg
+	if len(list) > s.messagesPerThread {
+		for _, old := range list[:len(list)-s.messagesPerThread] {
+			delete(s.messageIndex, old.ID)
+		}
+		list = append([]*StoredMessage(nil), list[len(list)-s.messagesPerThread:]...)
+	}
+	s.messages[msg.ThreadID] = list
+}
+
+// getThread returns a copy of a thread
+func (s *stateMirror) getThread(threadID int64) *Thread {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if thread, ok := s.threads[threadID]; ok {
+		return cloneThread(thread)
+	}
+	return nil
+}
+
+// listThreads returns copies of all threads in a folder (all folders if empty), most recently active first
+func (s *stateMirror) listThreads(folder string) []*Thread {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	threads := make([]*Thread, 0, len(s.threads))
+	for _, thread := range s.threads {
+		if folder != "" && thread.Folder != folder {
+			continue
+		}
+		threads = append(threads, cloneThread(thread))
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].LastActivityTimestampMs > threads[j].LastActivityTimestampMs
+	})
+	return threads
+}
+
+// getMessage returns a copy of a message
+func (s *stateMirror) getMessage(messageID string) *StoredMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if msg, ok := s.messageIndex[messageID]; ok {
+		clone := *msg
+		return &clone
+	}
+	return nil
+}
+
+// getContact returns a copy of a contact
+func (s *stateMirror) getContact(userID int64) *ContactInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if contact, ok := s.contacts[userID]; ok {
+		clone := *contact
+		return &clone
+	}
+	return nil
+}
+
+func findParticipant(participants []*ThreadParticipant, userID int64) *ThreadParticipant {
+	for _, p := range participants {
+		if p.UserID == userID {
+			return p
+		}
+	}
+	return nil
+}
+
+func cloneThread(thread *Thread) *Thread {
+	clone := *thread
+	clone.Participants = make([]*ThreadParticipant, len(thread.Participants))
+	for i, p := range thread.Participants {
+		participant := *p
+		clone.Participants[i] = &participant
+	}
+	return &clone
+}
+
+// GetThread returns a thread from the state mirror
+func (c *Client) GetThread(threadID int64) (*Thread, error) {
+	if thread := c.state.getThread(threadID); thread != nil {
+		return thread, nil
+	}
+	return nil, fmt.Errorf("thread not found: %d", threadID)
+}
+
+// ListThreads returns threads known to the state mirror, optionally filtered by folder
+func (c *Client) ListThreads(folder string) []*Thread {
+	return c.state.listThreads(folder)
+}
+
+// GetMessage returns a recent message from the state mirror
+func (c *Client) GetMessage(messageID string) (*StoredMessage, error) {
+	if msg := c.state.getMessage(messageID); msg != nil {
+		return msg, nil
+	}
+	return nil, fmt.Errorf("message not found: %s", messageID)
+}
+
+// GetContact returns a contact from the state mirror
+func (c *Client) GetContact(userID int64) (*ContactInfo, error) {
+	if contact := c.state.getContact(userID); contact != nil {
+		return contact, nil
+	}
+	return nil, fmt.Errorf("contact not found: %d", userID)
+}
+
+// applyEdit updates a message's text and returns its state before the edit (nil if unknown)
+// and the new edit count
+func (s *stateMirror) applyEdit(messageID, text string, editCount int64) (*StoredMessage, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messageIndex[messageID]
+	if !ok {
+		if editCount <= 0 {
+			editCount = 1
+		}
+		return nil, editCount
+	}
+	previous := *msg
+	if editCount <= 0 {
+		editCount = msg.EditCount + 1
+	}
+	msg.Text = text
+	msg.EditCount = editCount
+	return &previous, editCount
+}
+
+// applyUnsend marks a message as unsent and returns its state before (nil if unknown)
+func (s *stateMirror) applyUnsend(messageID string) *StoredMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messageIndex[messageID]
+	if !ok {
+		return nil
+	}
+	previous := *msg
+	msg.IsUnsent = true
+	return &previous
+}
+
+// applyPoll records the latest state of a poll and returns the state before (nil if
+// the poll wasn't known) and whether it changed
+func (s *stateMirror) applyPoll(poll *Poll) (*Poll, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.polls[poll.ID]
+	if previous != nil && pollsEqual(previous, poll) {
+		return previous, false
+	}
+	s.polls[poll.ID] = clonePoll(poll)
+	return previous, true
+}
+
+func pollsEqual(a, b *Poll) bool {
+	if a.Question != b.Question || len(a.Options) != len(b.Options) {
+		return false
+	}
+	for i := range a.Options {
+		if *a.Options[i] != *b.Options[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func clonePoll(poll *Poll) *Poll {
+	clone := *poll
+	clone.Options = make([]*PollOption, len(poll.Options))
+	for i, o := range poll.Options {
+		option := *o
+		clone.Options[i] = &option
+	}
+	return &clone
+}
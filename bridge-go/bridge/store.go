@@ -3,6 +3,7 @@ package bridge
 import (
 	"context"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.mau.fi/mautrix-meta/pkg/messagix"
@@ -17,11 +18,28 @@ func (ds *DeviceStore) PutIdentity(ctx context.Context, address string, key [32]
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 	ds.identities[address] = key
+	if pending, ok := ds.pendingIdentities[address]; ok && pending == key {
+		delete(ds.pendingIdentities, address)
+	}
 	go ds.Save()
 	return nil
 }
 
 func (ds *DeviceStore) DeleteAllIdentities(ctx context.Context, phone string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	prefix := phone + ":"
+	for address := range ds.identities {
+		if strings.HasPrefix(address, prefix) {
+			delete(ds.identities, address)
+		}
+	}
+	for address := range ds.pendingIdentities {
+		if strings.HasPrefix(address, prefix) {
+			delete(ds.pendingIdentities, address)
+		}
+	}
+	go ds.Save()
 	return nil
 }
 
@@ -29,18 +47,45 @@ func (ds *DeviceStore) DeleteIdentity(ctx context.Context, address string) error
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 	delete(ds.identities, address)
+	delete(ds.pendingIdentities, address)
 	go ds.Save()
 	return nil
 }
 
 func (ds *DeviceStore) IsTrustedIdentity(ctx context.Context, address string, key [32]byte) (bool, error) {
-	ds.mu.RLock()
-	defer ds.mu.RUnlock()
+	ds.mu.Lock()
 	existing, ok := ds.identities[address]
-	if !ok {
+	if !ok || existing == key {
+		ds.mu.Unlock()
 		return true, nil
 	}
-	return existing == key, nil
+
+	// The key changed: only notify once per new key
+	alreadyNotified := false
+	if pending, ok := ds.pendingIdentities[address]; ok && pending == key {
+		alreadyNotified = true
+	} else {
+		ds.pendingIdentities[address] = key
+		go ds.Save()
+	}
+	trusted := ds.trustPolicy == IdentityTrustAlways
+	evt := &IdentityChangedEvent{
+		Address:         address,
+		UserID:          addressUserID(address),
+		OldFingerprint:  identityFingerprint(existing),
+		NewFingerprint:  identityFingerprint(key),
+		Policy:          ds.trustPolicy,
+		Trusted:         ds.trustPolicy != IdentityTrustManual,
+		PendingApproval: ds.trustPolicy == IdentityTrustManual,
+		TimestampMs:     timeNowMs(),
+	}
+	callback := ds.onIdentityChanged
+	ds.mu.Unlock()
+
+	if !alreadyNotified && callback != nil {
+		callback(evt)
+	}
+	return trusted, nil
 }
 
 func (ds *DeviceStore) GetSession(ctx context.Context, address string) ([]byte, error) {
@@ -87,6 +132,15 @@ func (ds *DeviceStore) PutManySessions(ctx context.Context, sessions map[string]
 }
 
 func (ds *DeviceStore) DeleteAllSessions(ctx context.Context, phone string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	prefix := phone + ":"
+	for address := range ds.sessions {
+		if strings.HasPrefix(address, prefix) {
+			delete(ds.sessions, address)
+		}
+	}
+	go ds.Save()
 	return nil
 }
 
@@ -102,11 +156,17 @@ func (ds *DeviceStore) MigratePNToLID(ctx context.Context, pn, lid waTypes.JID)
 	return nil
 }
 
+// GetOrGenPreKeys returns prekeys that haven't been uploaded yet, generating more if needed
 func (ds *DeviceStore) GetOrGenPreKeys(ctx context.Context, count uint32) ([]*keys.PreKey, error) {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 	result := make([]*keys.PreKey, 0, count)
-	for i := uint32(0); i < count; i++ {
+	for id := ds.uploadedPreKeyID + 1; id < ds.nextPreKeyID && uint32(len(result)) < count; id++ {
+		if pk, ok := ds.preKeys[id]; ok {
+			result = append(result, pk)
+		}
+	}
+	for uint32(len(result)) < count {
 		pk := keys.NewPreKey(ds.nextPreKeyID)
 		ds.preKeys[ds.nextPreKeyID] = pk
 		result = append(result, pk)
@@ -116,12 +176,16 @@ func (ds *DeviceStore) GetOrGenPreKeys(ctx context.Context, count uint32) ([]*ke
 	return result, nil
 }
 
+// GenOnePreKey generates a prekey that is handed out directly (e.g. in pairing), so it counts as uploaded
 func (ds *DeviceStore) GenOnePreKey(ctx context.Context) (*keys.PreKey, error) {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 	pk := keys.NewPreKey(ds.nextPreKeyID)
 	ds.preKeys[ds.nextPreKeyID] = pk
 	ds.nextPreKeyID++
+	if pk.KeyID > ds.uploadedPreKeyID {
+		ds.uploadedPreKeyID = pk.KeyID
+	}
 	go ds.Save()
 	return pk, nil
 }
@@ -141,13 +205,25 @@ func (ds *DeviceStore) RemovePreKey(ctx context.Context, id uint32) error {
 }
 
 func (ds *DeviceStore) MarkPreKeysAsUploaded(ctx context.Context, upToID uint32) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if upToID > ds.uploadedPreKeyID {
+		ds.uploadedPreKeyID = upToID
+		go ds.Save()
+	}
 	return nil
 }
 
 func (ds *DeviceStore) UploadedPreKeyCount(ctx context.Context) (int, error) {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
-	return len(ds.preKeys), nil
+	count := 0
+	for id := range ds.preKeys {
+		if id <= ds.uploadedPreKeyID {
+			count++
+		}
+	}
+	return count, nil
 }
 
 func (ds *DeviceStore) PutSenderKey(ctx context.Context, group, user string, session []byte) error {
@@ -297,23 +373,67 @@ func (ds *DeviceStore) GetManyLIDsForPNs(ctx context.Context, pns []waTypes.JID)
 	return nil, nil
 }
 
+// bufferedEventTTL is how long ciphertext hashes are kept for deduplication.
+// The server only buffers undelivered events for 14 days, so older hashes can't be replayed.
+const bufferedEventTTL = 14 * 24 * time.Hour
+
 func (ds *DeviceStore) GetBufferedEvent(ctx context.Context, ciphertextHash [32]byte) (*store.BufferedEvent, error) {
-	return nil, nil
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	buf, ok := ds.bufferedEvents[ciphertextHash]
+	if !ok {
+		return nil, nil
+	}
+	// Return a copy so callers can't mutate the stored entry
+	bufCopy := *buf
+	return &bufCopy, nil
 }
 
 func (ds *DeviceStore) PutBufferedEvent(ctx context.Context, ciphertextHash [32]byte, plaintext []byte, serverTimestamp time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.bufferedEvents[ciphertextHash] = &store.BufferedEvent{
+		Plaintext:  plaintext,
+		InsertTime: time.Now(),
+		ServerTime: serverTimestamp,
+	}
+	go ds.Save()
 	return nil
 }
 
+// DoDecryptionTxn serializes decryption so that no other decryption runs between
+// a session update and buffering the plaintext for the same ciphertext. It is not
+// a transaction: nothing is rolled back if fn fails, and each write is saved on its own.
 func (ds *DeviceStore) DoDecryptionTxn(ctx context.Context, fn func(context.Context) error) error {
+	ds.decryptionMu.Lock()
+	defer ds.decryptionMu.Unlock()
 	return fn(ctx)
 }
 
 func (ds *DeviceStore) ClearBufferedEventPlaintext(ctx context.Context, ciphertextHash [32]byte) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if buf, ok := ds.bufferedEvents[ciphertextHash]; ok && buf.Plaintext != nil {
+		buf.Plaintext = nil
+		go ds.Save()
+	}
 	return nil
 }
 
 func (ds *DeviceStore) DeleteOldBufferedHashes(ctx context.Context) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	cutoff := time.Now().Add(-bufferedEventTTL)
+	deleted := false
+	for hash, buf := range ds.bufferedEvents {
+		if buf.InsertTime.Before(cutoff) {
+			delete(ds.bufferedEvents, hash)
+			deleted = true
+		}
+	}
+	if deleted {
+		go ds.Save()
+	}
 	return nil
 }
 
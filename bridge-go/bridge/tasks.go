@@ -0,0 +1,88 @@
+package bridge
+
+// LightSpeed tasks that messagix doesn't provide. Each implements socket.Task.
+// The labels, payload field names and queue names haven't been checked against
+// a capture of the web client; if the server rejects one of these tasks, this
+// is the place to fix it.
+
+// setAdminTask promotes or demotes a group participant.
+// Label 25, queue admin_status.
+type setAdminTask struct {
+	ThreadKey int64 `json:"thread_key"`
+	ContactID int64 `json:"contact_id"`
+	IsAdmin   int64 `json:"is_admin"` // 1 to promote, 0 to demote
+	SyncGroup int64 `json:"sync_group"`
+}
+
+func (t *setAdminTask) GetLabel() string {
+	return "25"
+}
+
+func (t *setAdminTask) Create() (interface{}, interface{}, bool) {
+	return t, "admin_status", false
+}
+
+// setApprovalModeTask toggles whether admins must approve new group participants.
+// Label 28, queue set_needs_admin_approval_for_new_participant.
+type setApprovalModeTask struct {
+	ThreadKey int64 `json:"thread_key"`
+	Value     int64 `json:"value"` // 1 to require approval, 0 to disable
+	SyncGroup int64 `json:"sync_group"`
+}
+
+func (t *setApprovalModeTask) GetLabel() string {
+	return "28"
+}
+
+func (t *setApprovalModeTask) Create() (interface{}, interface{}, bool) {
+	return t, "set_needs_admin_approval_for_new_participant", false
+}
+
+// setNicknameTask sets or clears a participant's nickname in a thread.
+// Label 44, queue thread_participant_nickname.
+type setNicknameTask struct {
+	ThreadKey int64  `json:"thread_key"`
+	ContactID int64  `json:"contact_id"`
+	Nickname  string `json:"nickname"`
+	SyncGroup int64  `json:"sync_group"`
+}
+
+func (t *setNicknameTask) GetLabel() string {
+	return "44"
+}
+
+func (t *setNicknameTask) Create() (interface{}, interface{}, bool) {
+	return t, "thread_participant_nickname", false
+}
+
+// setThemeTask changes a thread's color theme.
+// Label 43, queue thread_theme.
+type setThemeTask struct {
+	ThreadKey int64 `json:"thread_key"`
+	ThemeFBID int64 `json:"theme_fbid"`
+	SyncGroup int64 `json:"sync_group"`
+}
+
+func (t *setThemeTask) GetLabel() string {
+	return "43"
+}
+
+func (t *setThemeTask) Create() (interface{}, interface{}, bool) {
+	return t, "thread_theme", false
+}
+
+// setQuickReactionTask changes a thread's quick-reaction emoji.
+// Label 100003, queue thread_quick_reaction.
+type setQuickReactionTask struct {
+	ThreadKey   int64  `json:"thread_key"`
+	CustomEmoji string `json:"custom_emoji"`
+	SyncGroup   int64  `json:"sync_group"`
+}
+
+func (t *setQuickReactionTask) GetLabel() string {
+	return "100003"
+}
+
+func (t *setQuickReactionTask) Create() (interface{}, interface{}, bool) {
+	return t, "thread_quick_reaction", false
+}
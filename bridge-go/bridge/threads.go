@@ -0,0 +1,275 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/mautrix-meta/pkg/messagix/socket"
+	"go.mau.fi/mautrix-meta/pkg/messagix/table"
+)
+
+// ThreadFolder is a thread list folder
+type ThreadFolder string
+
+const (
+	ThreadFolderInbox    ThreadFolder = "inbox"
+	ThreadFolderPending  ThreadFolder = "pending" // message requests
+	ThreadFolderArchived ThreadFolder = "archived"
+	ThreadFolderSpam     ThreadFolder = "spam" // spam and filtered requests
+)
+
+// threadFolderInfo describes how a folder is fetched and recognized
+type threadFolderInfo struct {
+	ParentThreadKey int64
+	FolderNames     []string // LSDeleteThenInsertThread.FolderName values in the folder
+}
+
+var threadFolders = map[ThreadFolder]threadFolderInfo{
+	ThreadFolderInbox:    {ParentThreadKey: -1, FolderNames: []string{"inbox"}},
+	ThreadFolderPending:  {ParentThreadKey: -10, FolderNames: []string{"pending"}},
+	ThreadFolderArchived: {ParentThreadKey: -2, FolderNames: []string{"archived"}},
+	ThreadFolderSpam:     {ParentThreadKey: -3, FolderNames: []string{"spam", "other"}},
+}
+
+const (
+	defaultFetchThreadsLimit = 20
+	maxFetchThreadsLimit     = 200
+)
+
+// FetchThreadsOptions for listing threads in a folder
+type FetchThreadsOptions struct {
+	Folder ThreadFolder `json:"folder,omitempty"` // Defaults to inbox
+	Cursor string       `json:"cursor,omitempty"` // From a previous ThreadPage
+	Limit  int          `json:"limit,omitempty"`
+}
+
+// ThreadPage is a page of threads, most recently active first
+type ThreadPage struct {
+	Threads []*Thread `json:"threads"`
+	Cursor  string    `json:"cursor,omitempty"` // Pass to FetchThreads for older threads
+	HasMore bool      `json:"hasMore"`
+}
+
+// threadCursor points at the least recently active thread returned so far
+type threadCursor struct {
+	LastActivityTimestampMs int64
+	ThreadKey               int64
+}
+
+func (tc threadCursor) String() string {
+	return strconv.FormatInt(tc.LastActivityTimestampMs, 10) + ":" + strconv.FormatInt(tc.ThreadKey, 10)
+}
+
+func parseThreadCursor(s string) (threadCursor, error) {
+	ts, key, ok := strings.Cut(s, ":")
+	if !ok {
+		return threadCursor{}, fmt.Errorf("invalid cursor")
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return threadCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	threadKey, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return threadCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return threadCursor{LastActivityTimestampMs: timestamp, ThreadKey: threadKey}, nil
+}
+
+// FetchThreads lists threads in a folder, paging towards older activity
+func (c *Client) FetchThreads(opts *FetchThreadsOptions) (*ThreadPage, error) {
+	folder := opts.Folder
+	if folder == "" {
+		folder = ThreadFolderInbox
+	}
+	info, ok := threadFolders[folder]
+	if !ok {
+		return nil, fmt.Errorf("unknown thread folder: %s", folder)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultFetchThreadsLimit
+	} else if limit > maxFetchThreadsLimit {
+		limit = maxFetchThreadsLimit
+	}
+
+	ref := threadCursor{LastActivityTimestampMs: timeNowMs()}
+	if opts.Cursor != "" {
+		var err error
+		if ref, err = parseThreadCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	page := &ThreadPage{Threads: []*Thread{}}
+	seen := make(map[int64]bool)
+	hasMore := true
+	for len(page.Threads) < limit && hasMore {
+		task := &socket.FetchThreadsTask{
+			IsAfter:                    0,
+			ParentThreadKey:            info.ParentThreadKey,
+			ReferenceThreadKey:         ref.ThreadKey,
+			ReferenceActivityTimestamp: ref.LastActivityTimestampMs,
+			AdditionalPagesToFetch:     0,
+			Cursor:                     c.Messagix.SyncManager.GetCursor(1),
+			SyncGroup:                  1,
+		}
+		tbl, err := c.Messagix.ExecuteTasks(c.ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		if tbl == nil {
+			break
+		}
+
+		added := 0
+		oldest := ref
+		for _, t := range c.threadsFromTable(tbl) {
+			if seen[t.ID] || t.ID == ref.ThreadKey {
+				continue
+			}
+			seen[t.ID] = true
+			added++
+			if oldest == ref || t.LastActivityTimestampMs < oldest.LastActivityTimestampMs {
+				oldest = threadCursor{LastActivityTimestampMs: t.LastActivityTimestampMs, ThreadKey: t.ID}
+			}
+			// Responses may include threads from other folders, e.g. the thread a message request was accepted into
+			if t.Folder != "" && !folderContains(info, t.Folder) {
+				continue
+			}
+			page.Threads = append(page.Threads, t)
+		}
+
+		hasMore = false
+		for _, r := range tbl.LSUpsertSyncGroupThreadsRange {
+			if r.ParentThreadKey == info.ParentThreadKey {
+				hasMore = r.HasMoreBefore
+			}
+		}
+		if added == 0 {
+			break
+		}
+		ref = oldest
+	}
+
+	sort.SliceStable(page.Threads, func(i, j int) bool {
+		return page.Threads[i].LastActivityTimestampMs > page.Threads[j].LastActivityTimestampMs
+	})
+	if len(page.Threads) > limit {
+		page.Threads = page.Threads[:limit]
+		hasMore = true
+	}
+
+	if len(page.Threads) > 0 {
+		oldest := page.Threads[len(page.Threads)-1]
+		page.HasMore = hasMore
+		if hasMore {
+			page.Cursor = threadCursor{LastActivityTimestampMs: oldest.LastActivityTimestampMs, ThreadKey: oldest.ID}.String()
+		}
+	}
+	return page, nil
+}
+
+func folderContains(info threadFolderInfo, folderName string) bool {
+	for _, name := range info.FolderNames {
+		if name == folderName {
+			return true
+		}
+	}
+	return false
+}
+
+// threadsFromTable builds threads from the thread rows of a table, applying the
+// participant, name, picture and mute rows that arrived in the same batch
+func (c *Client) threadsFromTable(tbl *table.LSTable) []*Thread {
+	var threads []*Thread
+	byKey := make(map[int64]*Thread)
+	addThread := func(t *table.LSDeleteThenInsertThread) {
+		thread := convertThread(t)
+		if existing, ok := byKey[thread.ID]; ok {
+			*existing = *thread
+			return
+		}
+		byKey[thread.ID] = thread
+		threads = append(threads, thread)
+	}
+	for _, t := range tbl.LSDeleteThenInsertThread {
+		addThread(t)
+	}
+	for _, t := range tbl.LSUpdateOrInsertThread {
+		addThread((*table.LSDeleteThenInsertThread)(t))
+	}
+
+	for _, p := range tbl.LSAddParticipantIdToGroupThread {
+		thread := byKey[p.ThreadKey]
+		if thread == nil {
+			continue
+		}
+		participant := &ThreadParticipant{
+			UserID:                        p.ContactId,
+			Nickname:                      p.Nickname,
+			IsAdmin:                       p.IsAdmin,
+			IsSuperAdmin:                  p.IsSuperAdmin,
+			ReadWatermarkTimestampMs:      p.ReadWatermarkTimestampMs,
+			DeliveredWatermarkTimestampMs: p.DeliveredWatermarkTimestampMs,
+		}
+		replaced := false
+		for i, existing := range thread.Participants {
+			if existing.UserID == p.ContactId {
+				thread.Participants[i] = participant
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			thread.Participants = append(thread.Participants, participant)
+		}
+	}
+	for _, p := range tbl.LSRemoveParticipantFromThread {
+		if thread := byKey[p.ThreadKey]; thread != nil {
+			thread.Participants = removeParticipant(thread.Participants, p.ParticipantId)
+		}
+	}
+	for _, u := range tbl.LSSyncUpdateThreadName {
+		if thread := byKey[u.ThreadKey]; thread != nil {
+			thread.Name = u.ThreadName
+		}
+	}
+	for _, u := range tbl.LSSetThreadImageURL {
+		if thread := byKey[u.ThreadKey]; thread != nil {
+			thread.PictureURL = u.ImageURL
+		}
+	}
+	for _, u := range tbl.LSUpdateThreadMuteSetting {
+		if thread := byKey[u.ThreadKey]; thread != nil {
+			thread.MutedUntilMs = u.MuteExpireTimeMS
+		}
+	}
+
+	// Count unread messages from other people among the messages in this batch
+	for _, m := range tbl.LSUpsertMessage {
+		if thread := byKey[m.ThreadKey]; thread != nil && m.SenderId != c.FBID &&
+			m.TimestampMs > thread.LastReadWatermarkTimestampMs {
+			thread.UnreadCount++
+		}
+	}
+
+	for _, thread := range threads {
+		if thread.MemberCount == 0 && len(thread.Participants) > 0 {
+			thread.MemberCount = int64(len(thread.Participants))
+		}
+	}
+	return threads
+}
+
+func removeParticipant(participants []*ThreadParticipant, userID int64) []*ThreadParticipant {
+	result := participants[:0]
+	for _, p := range participants {
+		if p.UserID != userID {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package bridge
+
+import "testing"
+
+func TestThreadCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  string
+		want    threadCursor
+		wantErr bool
+	}{
+		{"timestamp and key", "1700000000000:123456789", threadCursor{LastActivityTimestampMs: 1700000000000, ThreadKey: 123456789}, false},
+		{"negative key", "10:-1", threadCursor{LastActivityTimestampMs: 10, ThreadKey: -1}, false},
+		{"no separator", "1700000000000", threadCursor{}, true},
+		{"bad timestamp", "x:1", threadCursor{}, true},
+		{"bad key", "1:x", threadCursor{}, true},
+		{"missing key", "1:", threadCursor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseThreadCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseThreadCursor(%q) error = %v, wantErr %v", tt.cursor, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("parseThreadCursor(%q) = %+v, want %+v", tt.cursor, got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.cursor {
+				t.Fatalf("String() = %q, want %q", got.String(), tt.cursor)
+			}
+		})
+	}
+}
+
+func TestFolderContains(t *testing.T) {
+	tests := []struct {
+		folder     ThreadFolder
+		folderName string
+		want       bool
+	}{
+		{ThreadFolderInbox, "inbox", true},
+		{ThreadFolderInbox, "archived", false},
+		{ThreadFolderSpam, "spam", true},
+		{ThreadFolderSpam, "other", true},
+		{ThreadFolderPending, "", false},
+	}
+	for _, tt := range tests {
+		if got := folderContains(threadFolders[tt.folder], tt.folderName); got != tt.want {
+			t.Errorf("folderContains(%s, %q) = %v, want %v", tt.folder, tt.folderName, got, tt.want)
+		}
+	}
+}
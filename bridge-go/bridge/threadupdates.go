@@ -0,0 +1,119 @@
+package bridge
+
+import "net/url"
+
+// ThreadUpdateKind is the kind of change in a thread update event
+type ThreadUpdateKind string
+
+const (
+	ThreadUpdateRename              ThreadUpdateKind = "rename"
+	ThreadUpdatePhotoChanged        ThreadUpdateKind = "photoChanged"
+	ThreadUpdateParticipantsAdded   ThreadUpdateKind = "participantsAdded"
+	ThreadUpdateParticipantsRemoved ThreadUpdateKind = "participantsRemoved"
+	ThreadUpdateAdminChanged        ThreadUpdateKind = "adminChanged"
+	ThreadUpdateNicknameChanged     ThreadUpdateKind = "nicknameChanged"
+	ThreadUpdateThemeChanged        ThreadUpdateKind = "themeChanged"
+	ThreadUpdateEmojiChanged        ThreadUpdateKind = "emojiChanged"
+)
+
+// ThreadUpdateEvent is a change to a thread's settings or members, found by
+// comparing live LightSpeed thread and participant rows with the state mirror.
+//
+// Before and After hold the name, picture URL, nickname or emoji (strings),
+// the theme FBID (int64) or the admin flag (bool). They are null for
+// participant additions and removals, which list the users in UserIDs.
+type ThreadUpdateEvent struct {
+	ThreadID    int64            `json:"threadId"`
+	Kind        ThreadUpdateKind `json:"kind"`
+	ActorID     int64            `json:"actorId,omitempty"` // Sender of the matching admin message, if known
+	UserIDs     []int64          `json:"userIds,omitempty"` // Affected participants
+	Before      interface{}      `json:"before"`
+	After       interface{}      `json:"after"`
+	TimestampMs int64            `json:"timestampMs"`
+}
+
+// diffThread compares a thread's new row with its previous state. Participant
+// changes come from their own rows and are compared in stateMirror.applyTable.
+func diffThread(before, after *Thread) []*ThreadUpdateEvent {
+	var updates []*ThreadUpdateEvent
+	change := func(kind ThreadUpdateKind, old, new interface{}) {
+		updates = append(updates, &ThreadUpdateEvent{ThreadID: after.ID, Kind: kind, Before: old, After: new})
+	}
+	if before.Name != after.Name {
+		change(ThreadUpdateRename, before.Name, after.Name)
+	}
+	if !samePicture(before.PictureURL, after.PictureURL) {
+		change(ThreadUpdatePhotoChanged, before.PictureURL, after.PictureURL)
+	}
+	if before.ThemeFbid != after.ThemeFbid {
+		change(ThreadUpdateThemeChanged, before.ThemeFbid, after.ThemeFbid)
+	}
+	if before.CustomEmoji != after.CustomEmoji {
+		change(ThreadUpdateEmojiChanged, before.CustomEmoji, after.CustomEmoji)
+	}
+	return updates
+}
+
+// samePicture reports whether two picture URLs point at the same image. Picture
+// URLs are signed CDN URLs whose host and query change on every resync, the path
+// only changes with the image.
+func samePicture(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if a == "" || b == "" {
+		return false
+	}
+	urlA, errA := url.Parse(a)
+	urlB, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return urlA.Path == urlB.Path
+}
+
+// diffParticipant compares a participant's admin flag and nickname
+func diffParticipant(threadID int64, before, after *ThreadParticipant) []*ThreadUpdateEvent {
+	var updates []*ThreadUpdateEvent
+	if before.IsAdmin != after.IsAdmin {
+		updates = append(updates, &ThreadUpdateEvent{
+			ThreadID: threadID,
+			Kind:     ThreadUpdateAdminChanged,
+			UserIDs:  []int64{after.UserID},
+			Before:   before.IsAdmin,
+			After:    after.IsAdmin,
+		})
+	}
+	if before.Nickname != after.Nickname {
+		updates = append(updates, &ThreadUpdateEvent{
+			ThreadID: threadID,
+			Kind:     ThreadUpdateNicknameChanged,
+			UserIDs:  []int64{after.UserID},
+			Before:   before.Nickname,
+			After:    after.Nickname,
+		})
+	}
+	return updates
+}
+
+// emitThreadUpdates emits thread updates, attributing them to the sender of an
+// admin message in the same thread if the actor isn't known yet
+func (c *Client) emitThreadUpdates(updates []*ThreadUpdateEvent, messages []*Message) {
+	if len(updates) == 0 {
+		return
+	}
+	actors := make(map[int64]int64)
+	for _, msg := range messages {
+		if msg.IsAdminMsg {
+			actors[msg.ThreadID] = msg.SenderID
+		}
+	}
+	now := timeNowMs()
+	for _, update := range updates {
+		if update.ActorID == 0 {
+			update.ActorID = actors[update.ThreadID]
+		}
+		update.TimestampMs = now
+		c.emitEvent(EventTypeThreadUpdate, update)
+	}
+}
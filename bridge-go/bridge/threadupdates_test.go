@@ -0,0 +1,57 @@
+package bridge
+
+import "testing"
+
+func TestSamePicture(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"identical", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg?oh=a", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg?oh=a", true},
+		{"resigned", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg?oh=a&oe=1", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg?oh=b&oe=2", true},
+		{"other cdn host", "https://scontent-a.xx.fbcdn.net/v/t1/1_n.jpg?oh=a", "https://scontent-b.xx.fbcdn.net/v/t1/1_n.jpg?oh=b", true},
+		{"new image", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg?oh=a", "https://scontent.xx.fbcdn.net/v/t1/2_n.jpg?oh=a", false},
+		{"added", "", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg", false},
+		{"removed", "https://scontent.xx.fbcdn.net/v/t1/1_n.jpg", "", false},
+		{"both empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := samePicture(tt.a, tt.b); got != tt.want {
+				t.Fatalf("samePicture(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffThread(t *testing.T) {
+	base := Thread{ID: 1, Name: "a", PictureURL: "https://cdn/p/1.jpg?oh=x", ThemeFbid: 10, CustomEmoji: "👍"}
+	tests := []struct {
+		name   string
+		modify func(*Thread)
+		want   []ThreadUpdateKind
+	}{
+		{"unchanged", func(*Thread) {}, nil},
+		{"resigned picture", func(t *Thread) { t.PictureURL = "https://cdn/p/1.jpg?oh=y" }, nil},
+		{"rename", func(t *Thread) { t.Name = "b" }, []ThreadUpdateKind{ThreadUpdateRename}},
+		{"new picture", func(t *Thread) { t.PictureURL = "https://cdn/p/2.jpg?oh=y" }, []ThreadUpdateKind{ThreadUpdatePhotoChanged}},
+		{"theme and emoji", func(t *Thread) { t.ThemeFbid = 11; t.CustomEmoji = "❤️" },
+			[]ThreadUpdateKind{ThreadUpdateThemeChanged, ThreadUpdateEmojiChanged}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := base, base
+			tt.modify(&after)
+			updates := diffThread(&before, &after)
+			if len(updates) != len(tt.want) {
+				t.Fatalf("got %d updates, want %d", len(updates), len(tt.want))
+			}
+			for i, update := range updates {
+				if update.Kind != tt.want[i] || update.ThreadID != base.ID {
+					t.Errorf("update %d = %s for %d, want %s for %d", i, update.Kind, update.ThreadID, tt.want[i], base.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+// Command devicectl inspects, backs up, restores and compares E2EE device data
+// (the DeviceJSON format used by DevicePath files and the deviceData config option)
+// without connecting to Messenger.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"messagix-bridge/bridge"
+)
+
+const passphraseEnv = "DEVICECTL_PASSPHRASE"
+
+const usage = `Usage: devicectl <command> [options]
+
+Commands:
+  inspect <source>                       Show a summary of device data
+  export [-o backup] <source>            Write an encrypted backup of device data
+  restore [-o target] [-force] <backup>  Decrypt a backup into a device file, or stdout with -o -
+  diff <source> <source>                 Compare two device snapshots (exit code 1 if they differ)
+
+A source is a file path, "-" for stdin, or an inline JSON string.
+The backup passphrase is read from $` + passphraseEnv + ` or the -passphrase-file option.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "inspect":
+		err = cmdInspect(os.Args[2:])
+	case "export":
+		err = cmdExport(os.Args[2:])
+	case "restore":
+		err = cmdRestore(os.Args[2:])
+	case "diff":
+		err = cmdDiff(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command: %s", os.Args[1])
+	}
+	if err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			os.Exit(int(exit))
+		}
+		fmt.Fprintln(os.Stderr, "devicectl:", err)
+		os.Exit(1)
+	}
+}
+
+// exitError exits with a status code without printing an error
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func cmdInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("inspect takes exactly one source")
+	}
+
+	deviceJSON, err := loadDevice(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printJSON(bridge.SummarizeDevice(deviceJSON))
+}
+
+func cmdExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.String("o", "-", "backup output path (- for stdout)")
+	passphraseFile := fs.String("passphrase-file", "", "read the passphrase from a file")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("export takes exactly one source")
+	}
+
+	data, err := readSource(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	backup, err := bridge.EncryptDeviceBackup(data, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, backup, true)
+}
+
+func cmdRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	output := fs.String("o", "-", "device file to write (- prints the deviceData string to stdout)")
+	force := fs.Bool("force", false, "overwrite an existing device file")
+	passphraseFile := fs.String("passphrase-file", "", "read the passphrase from a file")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("restore takes exactly one backup")
+	}
+
+	backup, err := readSource(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+	data, err := bridge.DecryptDeviceBackup(backup, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, data, *force)
+}
+
+func cmdDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("diff takes exactly two sources")
+	}
+
+	a, err := loadDevice(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	b, err := loadDevice(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(1), err)
+	}
+	diff := bridge.DiffDevices(a, b)
+	if err := printJSON(diff); err != nil {
+		return err
+	}
+	if !diff.Empty() {
+		return exitError(1)
+	}
+	return nil
+}
+
+// readSource reads a file path, stdin ("-") or an inline JSON string
+func readSource(source string) ([]byte, error) {
+	switch {
+	case source == "-":
+		return io.ReadAll(os.Stdin)
+	case strings.HasPrefix(strings.TrimSpace(source), "{"):
+		return []byte(source), nil
+	default:
+		return os.ReadFile(source)
+	}
+}
+
+func loadDevice(source string) (*bridge.DeviceJSON, error) {
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return bridge.ParseDeviceData(data)
+}
+
+func readPassphrase(path string) (string, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("no passphrase: set $%s or use -passphrase-file", passphraseEnv)
+}
+
+// writeOutput writes to stdout ("-") or a file that only the owner can read
+func writeOutput(path string, data []byte, overwrite bool) error {
+	if path == "-" {
+		_, err := fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use -force to overwrite", path)
+	} else if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -194,10 +194,11 @@ func MxSendMessage(input *C.char) *C.char {
 //export MxSendReaction
 func MxSendReaction(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		ThreadID  int64  `json:"threadId"`
-		MessageID string `json:"messageId"`
-		Emoji     string `json:"emoji"`
+		Handle         uint64 `json:"handle"`
+		ThreadID       int64  `json:"threadId"`
+		MessageID      string `json:"messageId"`
+		Emoji          string `json:"emoji"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -210,7 +211,7 @@ func MxSendReaction(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.SendReaction(payload.ThreadID, payload.MessageID, payload.Emoji); err != nil {
+	if err := client.SendReaction(payload.ThreadID, payload.MessageID, payload.Emoji, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -220,9 +221,10 @@ func MxSendReaction(input *C.char) *C.char {
 //export MxEditMessage
 func MxEditMessage(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		MessageID string `json:"messageId"`
-		NewText   string `json:"newText"`
+		Handle         uint64 `json:"handle"`
+		MessageID      string `json:"messageId"`
+		NewText        string `json:"newText"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -235,7 +237,7 @@ func MxEditMessage(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.EditMessage(payload.MessageID, payload.NewText); err != nil {
+	if err := client.EditMessage(payload.MessageID, payload.NewText, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -245,8 +247,9 @@ func MxEditMessage(input *C.char) *C.char {
 //export MxUnsendMessage
 func MxUnsendMessage(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		MessageID string `json:"messageId"`
+		Handle         uint64 `json:"handle"`
+		MessageID      string `json:"messageId"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -259,7 +262,7 @@ func MxUnsendMessage(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.UnsendMessage(payload.MessageID); err != nil {
+	if err := client.UnsendMessage(payload.MessageID, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -690,11 +693,19 @@ func MxPollEvents(input *C.char) *C.char {
 //export MxSendE2EEMessage
 func MxSendE2EEMessage(input *C.char) *C.char {
 	var payload struct {
-		Handle           uint64 `json:"handle"`
-		ChatJID          string `json:"chatJid"`
-		Text             string `json:"text"`
-		ReplyToID        string `json:"replyToId,omitempty"`
-		ReplyToSenderJID string `json:"replyToSenderJid,omitempty"`
+		Handle           uint64                 `json:"handle"`
+		ChatJID          string                 `json:"chatJid"`
+		Text             string                 `json:"text"`
+		ReplyToID        string                 `json:"replyToId,omitempty"`
+		ReplyToSenderJID string                 `json:"replyToSenderJid,omitempty"`
+		MentionIDs       []int64                `json:"mentionIds,omitempty"`
+		MentionOffsets   []int                  `json:"mentionOffsets,omitempty"`
+		MentionLengths   []int                  `json:"mentionLengths,omitempty"`
+		MentionMarkup    bool                   `json:"mentionMarkup,omitempty"`
+		Mentions         []*bridge.NamedMention `json:"mentions,omitempty"`
+		MentionEveryone  bool                   `json:"mentionEveryone,omitempty"`
+		LinkPreview      *bridge.LinkPreview    `json:"linkPreview,omitempty"`
+		IdempotencyKey   string                 `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -713,6 +724,14 @@ func MxSendE2EEMessage(input *C.char) *C.char {
 		E2EEChatJID:          payload.ChatJID,
 		E2EEReplyToID:        payload.ReplyToID,
 		E2EEReplyToSenderJID: payload.ReplyToSenderJID,
+		MentionIDs:           payload.MentionIDs,
+		MentionOffsets:       payload.MentionOffsets,
+		MentionLengths:       payload.MentionLengths,
+		MentionMarkup:        payload.MentionMarkup,
+		Mentions:             payload.Mentions,
+		MentionEveryone:      payload.MentionEveryone,
+		LinkPreview:          payload.LinkPreview,
+		IdempotencyKey:       payload.IdempotencyKey,
 	})
 	if err != nil {
 		return fail(err)
@@ -724,11 +743,12 @@ func MxSendE2EEMessage(input *C.char) *C.char {
 //export MxSendE2EEReaction
 func MxSendE2EEReaction(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		ChatJID   string `json:"chatJid"`
-		MessageID string `json:"messageId"`
-		SenderJID string `json:"senderJid"`
-		Emoji     string `json:"emoji"`
+		Handle         uint64 `json:"handle"`
+		ChatJID        string `json:"chatJid"`
+		MessageID      string `json:"messageId"`
+		SenderJID      string `json:"senderJid"`
+		Emoji          string `json:"emoji"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -741,7 +761,7 @@ func MxSendE2EEReaction(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.SendE2EEReaction(payload.ChatJID, payload.MessageID, payload.SenderJID, payload.Emoji); err != nil {
+	if err := client.SendE2EEReaction(payload.ChatJID, payload.MessageID, payload.SenderJID, payload.Emoji, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -776,10 +796,11 @@ func MxSendE2EETyping(input *C.char) *C.char {
 //export MxEditE2EEMessage
 func MxEditE2EEMessage(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		ChatJID   string `json:"chatJid"`
-		MessageID string `json:"messageId"`
-		NewText   string `json:"newText"`
+		Handle         uint64 `json:"handle"`
+		ChatJID        string `json:"chatJid"`
+		MessageID      string `json:"messageId"`
+		NewText        string `json:"newText"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -792,7 +813,7 @@ func MxEditE2EEMessage(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.EditE2EEMessage(payload.ChatJID, payload.MessageID, payload.NewText); err != nil {
+	if err := client.EditE2EEMessage(payload.ChatJID, payload.MessageID, payload.NewText, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -802,9 +823,10 @@ func MxEditE2EEMessage(input *C.char) *C.char {
 //export MxUnsendE2EEMessage
 func MxUnsendE2EEMessage(input *C.char) *C.char {
 	var payload struct {
-		Handle    uint64 `json:"handle"`
-		ChatJID   string `json:"chatJid"`
-		MessageID string `json:"messageId"`
+		Handle         uint64 `json:"handle"`
+		ChatJID        string `json:"chatJid"`
+		MessageID      string `json:"messageId"`
+		IdempotencyKey string `json:"idempotencyKey,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
 		return fail(fmt.Errorf("invalid json: %w", err))
@@ -817,7 +839,7 @@ func MxUnsendE2EEMessage(input *C.char) *C.char {
 		return fail(fmt.Errorf("client not found"))
 	}
 
-	if err := client.UnsendE2EEMessage(payload.ChatJID, payload.MessageID); err != nil {
+	if err := client.UnsendE2EEMessage(payload.ChatJID, payload.MessageID, payload.IdempotencyKey); err != nil {
 		return fail(err)
 	}
 
@@ -1059,4 +1081,952 @@ func MxRegisterPushNotifications(input *C.char) *C.char {
 	return success(map[string]interface{}{})
 }
 
+//export MxTrustIdentity
+func MxTrustIdentity(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64 `json:"handle"`
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.TrustIdentity(payload.Address); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxGetPendingIdentities
+func MxGetPendingIdentities(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	return success(map[string]interface{}{
+		"identities": client.GetPendingIdentities(),
+	})
+}
+
+//export MxResetE2EESession
+func MxResetE2EESession(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64 `json:"handle"`
+		ChatJID string `json:"chatJid"`
+		UserJID string `json:"userJid"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	jid := payload.UserJID
+	if jid == "" {
+		jid = payload.ChatJID
+	}
+	result, err := client.ResetE2EESession(jid)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxFetchMessages
+func MxFetchMessages(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                      `json:"handle"`
+		Options bridge.FetchMessagesOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.FetchMessages(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxFetchThreads
+func MxFetchThreads(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                     `json:"handle"`
+		Options bridge.FetchThreadsOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.FetchThreads(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxGetThread
+func MxGetThread(input *C.char) *C.char {
+	var payload struct {
+		Handle   uint64 `json:"handle"`
+		ThreadID int64  `json:"threadId"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	thread, err := client.GetThread(payload.ThreadID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(thread)
+}
+
+//export MxListThreads
+func MxListThreads(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+		Folder string `json:"folder,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	return success(map[string]interface{}{
+		"threads": client.ListThreads(payload.Folder),
+	})
+}
+
+//export MxGetMessage
+func MxGetMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle    uint64 `json:"handle"`
+		MessageID string `json:"messageId"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	msg, err := client.GetMessage(payload.MessageID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(msg)
+}
+
+//export MxGetContact
+func MxGetContact(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+		UserID int64  `json:"userId"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	contact, err := client.GetContact(payload.UserID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(contact)
+}
+
+//export MxExportThread
+func MxExportThread(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                     `json:"handle"`
+		Options bridge.ExportThreadOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.ExportThread(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxForwardMessage
+func MxForwardMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                       `json:"handle"`
+		Options bridge.ForwardMessageOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.ForwardMessage(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxForwardE2EEMessage
+func MxForwardE2EEMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                           `json:"handle"`
+		Options bridge.ForwardE2EEMessageOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.ForwardE2EEMessage(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxCreatePoll
+func MxCreatePoll(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                   `json:"handle"`
+		Options bridge.CreatePollOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.CreatePoll(&payload.Options); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxUpdatePoll
+func MxUpdatePoll(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                   `json:"handle"`
+		Options bridge.UpdatePollOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.UpdatePoll(&payload.Options); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxCreateGroup
+func MxCreateGroup(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                    `json:"handle"`
+		Options bridge.CreateGroupOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.CreateGroup(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxAddParticipants
+func MxAddParticipants(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                        `json:"handle"`
+		Options bridge.AddParticipantsOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.AddParticipants(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxRemoveParticipant
+func MxRemoveParticipant(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                          `json:"handle"`
+		Options bridge.RemoveParticipantOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.RemoveParticipant(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxLeaveGroup
+func MxLeaveGroup(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                   `json:"handle"`
+		Options bridge.LeaveGroupOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.LeaveGroup(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxSetAdmin
+func MxSetAdmin(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                 `json:"handle"`
+		Options bridge.SetAdminOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.SetAdmin(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxSetApprovalMode
+func MxSetApprovalMode(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                        `json:"handle"`
+		Options bridge.SetApprovalModeOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.SetApprovalMode(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxSetNickname
+func MxSetNickname(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                    `json:"handle"`
+		Options bridge.SetNicknameOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.SetNickname(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxSetThreadTheme
+func MxSetThreadTheme(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                       `json:"handle"`
+		Options bridge.SetThreadThemeOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.SetThreadTheme(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxSetThreadEmoji
+func MxSetThreadEmoji(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                       `json:"handle"`
+		Options bridge.SetThreadEmojiOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.SetThreadEmoji(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxGetMessageStatus
+func MxGetMessageStatus(input *C.char) *C.char {
+	var payload struct {
+		Handle     uint64 `json:"handle"`
+		TrackingID string `json:"trackingId"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	status, err := client.GetMessageStatus(payload.TrackingID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(status)
+}
+
+//export MxOutboxEnqueue
+func MxOutboxEnqueue(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                      `json:"handle"`
+		Options bridge.OutboxEnqueueOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.EnqueueOutbox(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxGetOutboxItem
+func MxGetOutboxItem(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+		ID     string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	item, err := client.GetOutboxItem(payload.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(item)
+}
+
+//export MxListOutbox
+func MxListOutbox(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64              `json:"handle"`
+		Status bridge.OutboxStatus `json:"status,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	return success(client.ListOutbox(payload.Status))
+}
+
+//export MxCancelOutboxItem
+func MxCancelOutboxItem(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+		ID     string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	item, err := client.CancelOutboxItem(payload.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(item)
+}
+
+//export MxScheduleMessage
+func MxScheduleMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                        `json:"handle"`
+		Options bridge.ScheduleMessageOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.ScheduleMessage(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxRescheduleMessage
+func MxRescheduleMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                          `json:"handle"`
+		Options bridge.RescheduleMessageOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.RescheduleMessage(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxListScheduledMessages
+func MxListScheduledMessages(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64                `json:"handle"`
+		Status bridge.ScheduleStatus `json:"status,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	return success(client.ListScheduledMessages(payload.Status))
+}
+
+//export MxCancelScheduledMessage
+func MxCancelScheduledMessage(input *C.char) *C.char {
+	var payload struct {
+		Handle uint64 `json:"handle"`
+		ID     string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	scheduled, err := client.CancelScheduledMessage(payload.ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(scheduled)
+}
+
+//export MxBuildMentions
+func MxBuildMentions(input *C.char) *C.char {
+	var opts bridge.BuildMentionsOptions
+	if err := json.Unmarshal([]byte(C.GoString(input)), &opts); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	result, err := bridge.BuildMentions(&opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxMarkE2EERead
+func MxMarkE2EERead(input *C.char) *C.char {
+	var payload struct {
+		Handle     uint64   `json:"handle"`
+		ChatJID    string   `json:"chatJid"`
+		MessageIDs []string `json:"messageIds"`
+		SenderJID  string   `json:"senderJid,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.MarkE2EERead(payload.ChatJID, payload.MessageIDs, payload.SenderJID); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxResolveThread
+func MxResolveThread(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64           `json:"handle"`
+		Options bridge.ThreadRef `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.ResolveThread(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxRegisterE2EEChat
+func MxRegisterE2EEChat(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64           `json:"handle"`
+		Options bridge.ThreadRef `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	result, err := client.RegisterE2EEChat(&payload.Options)
+	if err != nil {
+		return fail(err)
+	}
+
+	return success(result)
+}
+
+//export MxReact
+func MxReact(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64              `json:"handle"`
+		Options bridge.ReactOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.React(&payload.Options); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxSetTyping
+func MxSetTyping(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                  `json:"handle"`
+		Options bridge.SetTypingOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.SetTyping(&payload.Options); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
+//export MxMarkThreadRead
+func MxMarkThreadRead(input *C.char) *C.char {
+	var payload struct {
+		Handle  uint64                       `json:"handle"`
+		Options bridge.MarkThreadReadOptions `json:"options"`
+	}
+	if err := json.Unmarshal([]byte(C.GoString(input)), &payload); err != nil {
+		return fail(fmt.Errorf("invalid json: %w", err))
+	}
+
+	clientsMu.RLock()
+	client := clients[handle(payload.Handle)]
+	clientsMu.RUnlock()
+	if client == nil {
+		return fail(fmt.Errorf("client not found"))
+	}
+
+	if err := client.MarkThreadRead(&payload.Options); err != nil {
+		return fail(err)
+	}
+
+	return success(map[string]interface{}{})
+}
+
 func main() {}
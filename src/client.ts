@@ -26,12 +26,45 @@ import type {
     ClientOptions,
     Cookies,
     CreateThreadResult,
+    E2EEDecryptFailedData,
     E2EEMessage,
+    ExportThreadOptions,
+    ExportThreadResult,
+    FetchMessagesOptions,
+    FetchThreadsOptions,
+    GroupUpdateResult,
+    IdentityChangedData,
     InitialData,
+    LinkPreview,
+    MentionedText,
     Message,
+    MessageEditData,
+    MessagePage,
+    MessageStatus,
+    MessageUnsendData,
+    NamedMention,
+    OutboxItem,
+    OutboxKind,
+    OutboxPayloads,
+    OutboxStatus,
+    PendingIdentity,
+    PollUpdateData,
+    RateLimitedData,
+    ResolvedThread,
+    ScheduledMessage,
+    ScheduleStatus,
+    ScheduleTarget,
     SearchUserResult,
     SendMessageOptions,
     SendMessageResult,
+    SessionResetResult,
+    StoredMessage,
+    Thread,
+    ThreadFolder,
+    ThreadPage,
+    ThreadRef,
+    ThreadThemeName,
+    ThreadUpdateData,
     UploadMediaResult,
     User,
     UserInfo,
@@ -52,8 +85,8 @@ export interface ClientEventMap {
     disconnected: [{ isE2EE?: boolean }];
     error: [Error];
     message: [Message];
-    messageEdit: [{ messageId: string; threadId: bigint; newText: string; editCount?: bigint; timestampMs?: bigint }];
-    messageUnsend: [{ messageId: string; threadId: bigint }];
+    messageEdit: [MessageEditData];
+    messageUnsend: [MessageUnsendData];
     reaction: [{ messageId: string; threadId: bigint; actorId: bigint; reaction: string; timestampMs?: bigint }];
     typing: [{ threadId: bigint; senderId: bigint; isTyping: boolean }];
     readReceipt: [{ threadId: bigint; readerId: bigint; readWatermarkTimestampMs: bigint; timestampMs?: bigint }];
@@ -62,6 +95,15 @@ export interface ClientEventMap {
     e2eeReaction: [{ messageId: string; chatJid: string; senderJid: string; senderId?: bigint; reaction: string }];
     e2eeReceipt: [{ type: string; chat: string; sender: string; messageIds: string[] }];
     deviceDataChanged: [{ deviceData: string }];
+    e2eeDecryptFailed: [E2EEDecryptFailedData];
+    identityChanged: [IdentityChangedData];
+    pollUpdate: [PollUpdateData];
+    threadUpdate: [ThreadUpdateData];
+    messageStatus: [MessageStatus];
+    outboxUpdate: [OutboxItem];
+    rateLimited: [RateLimitedData];
+    scheduledMessageSent: [ScheduledMessage];
+    scheduledMessageFailed: [ScheduledMessage];
     raw: [{ from: "lightspeed" | "whatsmeow" | "internal"; type: string; data: unknown }];
 }
 
@@ -197,6 +239,14 @@ export class Client<
             deviceData: this.options.deviceData,
             e2eeMemoryOnly: this.options.e2eeMemoryOnly,
             logLevel: this.options.logLevel,
+            identityTrustPolicy: this.options.identityTrustPolicy,
+            idempotencyWindowSeconds: this.options.idempotencyWindowSeconds,
+            stateMessagesPerThread: this.options.stateMessagesPerThread,
+            archivePath: this.options.archivePath,
+            outboxPath: this.options.outboxPath,
+            rateLimit: this.options.rateLimit,
+            scheduledMessagesPath: this.options.scheduledMessagesPath,
+            autoMarkRead: this.options.autoMarkRead,
         });
         this.handle = handle;
 
@@ -245,36 +295,86 @@ export class Client<
     /**
      * Send a text message
      *
-     * @param threadId - Thread ID to send to
+     * Sends to E2EE threads go over E2EE, and fail if it isn't connected.
+     *
+     * @param thread - Thread ID or reference to send to
      * @param options - Message options (text, reply, mentions)
      * @returns Send result with message ID
      */
-    async sendMessage(threadId: bigint, options: SendMessageOptions | string): Promise<SendMessageResult> {
+    async sendMessage(thread: bigint | ThreadRef, options: SendMessageOptions | string): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
 
         const opts = typeof options === "string" ? { text: options } : options;
 
         return native.sendMessage(this.handle, {
-            threadId,
+            ...(typeof thread === "bigint" ? { threadId: thread } : { thread }),
             text: opts.text,
             replyToId: opts.replyToId,
             attachmentFbIds: opts.attachmentFbIds,
             mentionIds: opts.mentions?.map(m => m.userId),
             mentionOffsets: opts.mentions?.map(m => m.offset),
             mentionLengths: opts.mentions?.map(m => m.length),
+            mentionMarkup: opts.mentionMarkup,
+            mentions: opts.namedMentions,
+            idempotencyKey: opts.idempotencyKey,
         });
     }
 
+    /**
+     * Build mentions from `@[Name](id)` markup or from names in the text
+     *
+     * Offsets are computed in UTF-16 code units, so names with emoji line up.
+     * The result can be passed to sendMessage as is.
+     *
+     * @param text - Message text
+     * @param options - markup to replace `@[Name](id)` markup, or mentions to locate by name
+     * @returns Text and mentions
+     *
+     * @example
+     * ```typescript
+     * const built = client.buildMentions('Hi @[Alice 🌸](100001), meet @[Bob](100002)!', { markup: true })
+     * await client.sendMessage(threadId, built)
+     * ```
+     */
+    buildMentions(text: string, options: { markup?: boolean; mentions?: NamedMention[] }): MentionedText {
+        const result = native.buildMentions({ text, ...options });
+        return {
+            text: result.text,
+            mentions: result.mentionIds.map((userId, i) => ({
+                userId,
+                offset: Number(result.mentionOffsets[i]),
+                length: Number(result.mentionLengths[i]),
+            })),
+        };
+    }
+
+    /**
+     * Get the delivery state of a recent send
+     *
+     * @param trackingId - Tracking ID from the send result
+     * @returns Message status
+     */
+    getMessageStatus(trackingId: string): MessageStatus {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getMessageStatus(this.handle, trackingId) as MessageStatus;
+    }
+
     /**
      * Send / Remove a reaction to a message
      *
      * @param threadId - Thread ID
      * @param messageId - Message ID to react to
-     * @param emoji - Reaction emoji (to remove, simply omit this parameter)
+     * @param emoji - Reaction emoji (to remove, pass an empty string or omit this parameter)
+     * @param options - Optional: idempotencyKey
      */
-    async sendReaction(threadId: bigint, messageId: string, emoji?: string): Promise<void> {
+    async sendReaction(
+        threadId: bigint,
+        messageId: string,
+        emoji?: string,
+        options?: { idempotencyKey?: string },
+    ): Promise<void> {
         if (!this.handle) throw new Error("Not connected");
-        await native.sendReaction(this.handle, threadId, messageId, emoji || "");
+        await native.sendReaction(this.handle, threadId, messageId, emoji || "", options?.idempotencyKey);
     }
 
     /**
@@ -282,20 +382,73 @@ export class Client<
      *
      * @param messageId - Message ID to edit
      * @param newText - New text content
+     * @param options - Optional: idempotencyKey
      */
-    async editMessage(messageId: string, newText: string): Promise<void> {
+    async editMessage(messageId: string, newText: string, options?: { idempotencyKey?: string }): Promise<void> {
         if (!this.handle) throw new Error("Not connected");
-        await native.editMessage(this.handle, messageId, newText);
+        await native.editMessage(this.handle, messageId, newText, options?.idempotencyKey);
     }
 
     /**
      * Unsend/delete a message
      *
      * @param messageId - Message ID to unsend
+     * @param options - Optional: idempotencyKey
+     */
+    async unsendMessage(messageId: string, options?: { idempotencyKey?: string }): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.unsendMessage(this.handle, messageId, options?.idempotencyKey);
+    }
+
+    /**
+     * Forward a message to another thread
+     *
+     * @param toThreadId - Thread ID to forward to
+     * @param messageId - Message ID to forward
+     * @param options - Optional: idempotencyKey
+     * @returns Send result of the forwarded message
+     */
+    async forwardMessage(
+        toThreadId: bigint,
+        messageId: string,
+        options?: { idempotencyKey?: string },
+    ): Promise<SendMessageResult> {
+        if (!this.handle) throw new Error("Not connected");
+        return native.forwardMessage(this.handle, {
+            toThreadId,
+            forwardedMsgId: messageId,
+            idempotencyKey: options?.idempotencyKey,
+        });
+    }
+
+    /**
+     * Create a poll
+     *
+     * @param threadId - Thread ID
+     * @param question - Poll question
+     * @param options - Option texts
      */
-    async unsendMessage(messageId: string): Promise<void> {
+    async createPoll(threadId: bigint, question: string, options: string[]): Promise<void> {
         if (!this.handle) throw new Error("Not connected");
-        await native.unsendMessage(this.handle, messageId);
+        await native.createPoll(this.handle, { threadId, question, options });
+    }
+
+    /**
+     * Vote on a poll and/or add options to it
+     *
+     * @param threadId - Thread ID
+     * @param pollId - Poll ID
+     * @param selectedOptions - Option IDs to vote for, replacing previous votes (empty to remove votes)
+     * @param addedOptions - New option texts to add (optional)
+     */
+    async updatePoll(
+        threadId: bigint,
+        pollId: bigint,
+        selectedOptions: bigint[],
+        addedOptions?: string[],
+    ): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.updatePoll(this.handle, { threadId, pollId, selectedOptions, addedOptions });
     }
 
     /**
@@ -354,13 +507,13 @@ export class Client<
      * @param threadId - Thread ID
      * @param data - Image data as Buffer
      * @param filename - Filename
-     * @param options - Optional: caption and replyToId
+     * @param options - Optional: caption, replyToId and idempotencyKey
      */
     async sendImage(
         threadId: bigint,
         data: Buffer,
         filename: string,
-        options?: string | { caption?: string; replyToId?: string },
+        options?: string | { caption?: string; replyToId?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         const opts = typeof options === "string" ? { caption: options } : options;
@@ -370,6 +523,7 @@ export class Client<
             filename,
             caption: opts?.caption,
             replyToId: opts?.replyToId,
+            idempotencyKey: opts?.idempotencyKey,
         });
     }
 
@@ -379,13 +533,13 @@ export class Client<
      * @param threadId - Thread ID
      * @param data - Video data as Buffer
      * @param filename - Filename
-     * @param options - Optional: caption and replyToId
+     * @param options - Optional: caption, replyToId and idempotencyKey
      */
     async sendVideo(
         threadId: bigint,
         data: Buffer,
         filename: string,
-        options?: string | { caption?: string; replyToId?: string },
+        options?: string | { caption?: string; replyToId?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         const opts = typeof options === "string" ? { caption: options } : options;
@@ -395,6 +549,7 @@ export class Client<
             filename,
             caption: opts?.caption,
             replyToId: opts?.replyToId,
+            idempotencyKey: opts?.idempotencyKey,
         });
     }
 
@@ -404,13 +559,13 @@ export class Client<
      * @param threadId - Thread ID
      * @param data - Audio data as Buffer
      * @param filename - Filename
-     * @param options - Optional: replyToId
+     * @param options - Optional: replyToId and idempotencyKey
      */
     async sendVoice(
         threadId: bigint,
         data: Buffer,
         filename: string,
-        options?: { replyToId?: string },
+        options?: { replyToId?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         return native.sendVoice(this.handle, {
@@ -418,6 +573,7 @@ export class Client<
             data: Array.from(data),
             filename,
             replyToId: options?.replyToId,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -428,14 +584,14 @@ export class Client<
      * @param data - File data as Buffer
      * @param filename - Filename
      * @param mimeType - MIME type
-     * @param options - Optional: caption and replyToId
+     * @param options - Optional: caption, replyToId and idempotencyKey
      */
     async sendFile(
         threadId: bigint,
         data: Buffer,
         filename: string,
         mimeType: string,
-        options?: string | { caption?: string; replyToId?: string },
+        options?: string | { caption?: string; replyToId?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         const opts = typeof options === "string" ? { caption: options } : options;
@@ -446,6 +602,7 @@ export class Client<
             mimeType,
             caption: opts?.caption,
             replyToId: opts?.replyToId,
+            idempotencyKey: opts?.idempotencyKey,
         });
     }
 
@@ -454,15 +611,20 @@ export class Client<
      *
      * @param threadId - Thread ID
      * @param stickerId - Sticker ID
-     * @param options - Optional: replyToId
+     * @param options - Optional: replyToId and idempotencyKey
      */
     async sendSticker(
         threadId: bigint,
         stickerId: bigint,
-        options?: { replyToId?: string },
+        options?: { replyToId?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
-        return native.sendSticker(this.handle, { threadId, stickerId, replyToId: options?.replyToId });
+        return native.sendSticker(this.handle, {
+            threadId,
+            stickerId,
+            replyToId: options?.replyToId,
+            idempotencyKey: options?.idempotencyKey,
+        });
     }
 
     /**
@@ -543,6 +705,126 @@ export class Client<
         native.deleteThread(this.handle, { threadId });
     }
 
+    /**
+     * Create a group thread
+     *
+     * @param userIds - Participants other than yourself (at least two)
+     * @param name - Group name (optional)
+     * @returns Created group
+     */
+    async createGroup(userIds: bigint[], name?: string): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.createGroup(this.handle, { userIds, name });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Add people to a group
+     *
+     * In groups with approval mode on, additions by non-admins become requests for an admin to approve.
+     *
+     * @param threadId - Group thread ID
+     * @param userIds - User IDs to add
+     * @returns Updated group
+     */
+    async addParticipants(threadId: bigint, userIds: bigint[]): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.addParticipants(this.handle, { threadId, userIds });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Remove someone from a group
+     *
+     * @param threadId - Group thread ID
+     * @param userId - User ID to remove
+     * @returns Updated group
+     */
+    async removeParticipant(threadId: bigint, userId: bigint): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.removeParticipant(this.handle, { threadId, userId });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Leave a group
+     *
+     * @param threadId - Group thread ID
+     * @returns Updated group
+     */
+    async leaveGroup(threadId: bigint): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.leaveGroup(this.handle, { threadId });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Promote a participant to admin or demote them
+     *
+     * @param threadId - Group thread ID
+     * @param userId - User ID
+     * @param isAdmin - Whether to make them admin (default: true)
+     * @returns Updated group
+     */
+    async setAdmin(threadId: bigint, userId: bigint, isAdmin: boolean = true): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.setAdmin(this.handle, { threadId, userId, isAdmin });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Set whether admins must approve new participants
+     *
+     * @param threadId - Group thread ID
+     * @param enabled - Whether approval is required
+     * @returns Updated group
+     */
+    async setApprovalMode(threadId: bigint, enabled: boolean): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.setApprovalMode(this.handle, { threadId, enabled });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Set or clear a participant's nickname in a thread
+     *
+     * @param threadId - Thread ID
+     * @param userId - User ID
+     * @param nickname - New nickname (omit to clear)
+     * @returns Updated thread
+     */
+    async setNickname(threadId: bigint, userId: bigint, nickname?: string): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.setNickname(this.handle, { threadId, userId, nickname: nickname || "" });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Change a thread's color theme
+     *
+     * @param threadId - Thread ID
+     * @param theme - Theme name or any theme FBID, e.g. of a theme newer than the named ones
+     * @returns Updated thread
+     */
+    async setThreadTheme(threadId: bigint, theme: ThreadThemeName | bigint): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.setThreadTheme(this.handle, { threadId, theme: theme.toString() });
+        return result as GroupUpdateResult;
+    }
+
+    /**
+     * Change the emoji sent by a thread's quick reaction button
+     *
+     * @param threadId - Thread ID
+     * @param emoji - Emoji
+     * @returns Updated thread
+     */
+    async setThreadEmoji(threadId: bigint, emoji: string): Promise<GroupUpdateResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.setThreadEmoji(this.handle, { threadId, emoji });
+        return result as GroupUpdateResult;
+    }
+
     /**
      * Search for users
      *
@@ -555,6 +837,314 @@ export class Client<
         return result.users;
     }
 
+    /**
+     * Fetch older messages of a thread
+     *
+     * @param threadId - Thread ID
+     * @param options - Optional: cursor from a previous page or a timestamp to start before, and a limit
+     * @returns Page of messages, newest last
+     *
+     * @example
+     * ```typescript
+     * let page = await client.fetchMessages(threadId, { limit: 50 })
+     * while (page.hasMore) {
+     *     page = await client.fetchMessages(threadId, { cursor: page.cursor })
+     * }
+     * ```
+     */
+    async fetchMessages(threadId: bigint, options?: FetchMessagesOptions): Promise<MessagePage> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.fetchMessages(this.handle, { threadId, ...options });
+        return result as MessagePage;
+    }
+
+    /**
+     * Fetch the thread list of a folder
+     *
+     * @param options - Optional: folder (default: inbox), cursor from a previous page and a limit
+     * @returns Page of threads, most recently active first
+     */
+    async fetchThreads(options?: FetchThreadsOptions): Promise<ThreadPage> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.fetchThreads(this.handle, { ...options });
+        return result as ThreadPage;
+    }
+
+    // ========== Thread Routing ==========
+
+    /**
+     * Find where sends to a thread go.
+     * Known E2EE chats are kept in memory only, so after a restart a thread ID routes to E2EE
+     * once its chat has been synced again or registered with registerE2EEChat.
+     *
+     * @param thread - Thread reference
+     * @returns Resolved thread, with its chat JID if it's E2EE
+     */
+    resolveThread(thread: ThreadRef): ResolvedThread {
+        if (!this.handle) throw new Error("Not connected");
+        return native.resolveThread(this.handle, thread) as ResolvedThread;
+    }
+
+    /**
+     * Mark a thread as end-to-end encrypted, so sends to its thread ID go over E2EE.
+     * Threads seen in syncs or E2EE messages are registered automatically.
+     *
+     * @param chatJid - E2EE chat JID
+     * @param threadId - Thread ID, taken from the chat JID if omitted
+     * @returns Resolved thread
+     */
+    registerE2EEChat(chatJid: string, threadId?: bigint): ResolvedThread {
+        if (!this.handle) throw new Error("Not connected");
+        return native.registerE2EEChat(this.handle, { threadId, chatJid }) as ResolvedThread;
+    }
+
+    /**
+     * Send / Remove a reaction in any thread, over E2EE if the thread is encrypted
+     *
+     * @param thread - Thread reference
+     * @param messageId - Message ID to react to
+     * @param emoji - Reaction emoji (to remove, pass an empty string or omit this parameter)
+     * @param options - Optional: senderJid of the message in E2EE threads (looked up if omitted), idempotencyKey
+     */
+    async react(
+        thread: ThreadRef,
+        messageId: string,
+        emoji?: string,
+        options?: { senderJid?: string; idempotencyKey?: string },
+    ): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.react(this.handle, { thread, messageId, emoji: emoji || "", ...options });
+    }
+
+    /**
+     * Send a typing indicator in any thread, over E2EE if the thread is encrypted
+     *
+     * @param thread - Thread reference
+     * @param isTyping - Whether typing (default: true)
+     */
+    async setTyping(thread: ThreadRef, isTyping: boolean = true): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.setTyping(this.handle, { thread, isTyping });
+    }
+
+    /**
+     * Mark any thread as read, with read receipts for the given messages if it's encrypted
+     *
+     * @param thread - Thread reference
+     * @param options - Optional: watermarkTs for regular threads, messageIds and senderJid (groups) for E2EE threads
+     */
+    async markThreadRead(
+        thread: ThreadRef,
+        options?: { watermarkTs?: number; messageIds?: string[]; senderJid?: string },
+    ): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.markThreadRead(this.handle, { thread, ...options });
+    }
+
+    // ========== Outbox ==========
+
+    /**
+     * Queue a send in the outbox
+     *
+     * Items are sent in order per thread once the needed connection is up. Transport errors and timeouts are
+     * retried with backoff, other errors fail the item at once. Items get an idempotency key if they don't have
+     * one, so a retry doesn't send twice while the client runs. Keys aren't persisted, so a send interrupted by a
+     * restart is sent again: delivery is at least once.
+     * Progress is reported with `outboxUpdate` events.
+     *
+     * @param kind - Kind of send
+     * @param payload - Options of the matching send
+     * @returns Queued item
+     *
+     * @example
+     * ```typescript
+     * const item = client.outboxEnqueue('message', { threadId, text: 'Hello' })
+     * client.on('outboxUpdate', update => {
+     *     if (update.id === item.id && update.status === 'sent') console.log('Sent:', update.result)
+     * })
+     * ```
+     */
+    outboxEnqueue<K extends OutboxKind>(kind: K, payload: OutboxPayloads[K]): OutboxItem {
+        if (!this.handle) throw new Error("Not connected");
+        const encoded: Record<string, unknown> = { ...payload };
+        if (Buffer.isBuffer(encoded.data)) encoded.data = encoded.data.toString("base64");
+        const preview = (payload as OutboxPayloads["message"]).linkPreview;
+        if (preview?.thumbnail) encoded.linkPreview = { ...preview, thumbnail: preview.thumbnail.toString("base64") };
+        return native.outboxEnqueue(this.handle, { kind, payload: encoded }) as OutboxItem;
+    }
+
+    /**
+     * Get an outbox item
+     *
+     * @param id - Item ID
+     * @returns Outbox item
+     */
+    getOutboxItem(id: string): OutboxItem {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getOutboxItem(this.handle, id) as OutboxItem;
+    }
+
+    /**
+     * List outbox items, oldest first
+     *
+     * @param status - Only list items in this state (optional)
+     * @returns Outbox items
+     */
+    listOutbox(status?: OutboxStatus): OutboxItem[] {
+        if (!this.handle) throw new Error("Not connected");
+        return native.listOutbox(this.handle, status) as OutboxItem[];
+    }
+
+    /**
+     * Cancel an outbox item that hasn't been sent yet
+     *
+     * @param id - Item ID
+     * @returns Cancelled item
+     */
+    cancelOutboxItem(id: string): OutboxItem {
+        if (!this.handle) throw new Error("Not connected");
+        return native.cancelOutboxItem(this.handle, id) as OutboxItem;
+    }
+
+    // ========== Scheduled Messages ==========
+
+    /**
+     * Schedule a message to be sent at a set time
+     *
+     * Messages due while disconnected are sent as soon as the connection is back.
+     * Delivery is at least once: a send interrupted by a restart is sent again.
+     * The outcome is reported with `scheduledMessageSent` and `scheduledMessageFailed` events.
+     *
+     * @param target - Thread ID or E2EE chat JID
+     * @param sendAt - When to send
+     * @param options - Message text or options
+     * @returns Scheduled message
+     *
+     * @example
+     * ```typescript
+     * client.scheduleMessage({ threadId }, new Date(Date.now() + 60 * 60 * 1000), 'Reminder!')
+     * ```
+     */
+    scheduleMessage(
+        target: ScheduleTarget,
+        sendAt: Date | number,
+        options: SendMessageOptions | string,
+    ): ScheduledMessage {
+        if (!this.handle) throw new Error("Not connected");
+
+        const opts = typeof options === "string" ? { text: options } : options;
+
+        return native.scheduleMessage(this.handle, {
+            ...target,
+            content: {
+                text: opts.text,
+                replyToId: opts.replyToId,
+                attachmentFbIds: opts.attachmentFbIds,
+                mentionIds: opts.mentions?.map(m => m.userId),
+                mentionOffsets: opts.mentions?.map(m => m.offset),
+                mentionLengths: opts.mentions?.map(m => m.length),
+                mentionMarkup: opts.mentionMarkup,
+                mentions: opts.namedMentions,
+                idempotencyKey: opts.idempotencyKey,
+            },
+            sendAtMs: sendAt instanceof Date ? sendAt.getTime() : sendAt,
+        }) as ScheduledMessage;
+    }
+
+    /**
+     * Move a message that hasn't been sent yet to another time
+     *
+     * @param id - Scheduled message ID
+     * @param sendAt - When to send
+     * @returns Updated scheduled message
+     */
+    rescheduleMessage(id: string, sendAt: Date | number): ScheduledMessage {
+        if (!this.handle) throw new Error("Not connected");
+        const sendAtMs = sendAt instanceof Date ? sendAt.getTime() : sendAt;
+        return native.rescheduleMessage(this.handle, id, sendAtMs) as ScheduledMessage;
+    }
+
+    /**
+     * List scheduled messages
+     *
+     * @param status - Only list messages in this state (optional)
+     * @returns Scheduled messages
+     */
+    listScheduledMessages(status?: ScheduleStatus): ScheduledMessage[] {
+        if (!this.handle) throw new Error("Not connected");
+        return native.listScheduledMessages(this.handle, status) as ScheduledMessage[];
+    }
+
+    /**
+     * Cancel a message that hasn't been sent yet
+     *
+     * @param id - Scheduled message ID
+     * @returns Cancelled scheduled message
+     */
+    cancelScheduledMessage(id: string): ScheduledMessage {
+        if (!this.handle) throw new Error("Not connected");
+        return native.cancelScheduledMessage(this.handle, id) as ScheduledMessage;
+    }
+
+    // ========== State Mirror ==========
+
+    /**
+     * Get a thread known to the client, without a network request
+     *
+     * @param threadId - Thread ID
+     * @returns Thread
+     */
+    getThread(threadId: bigint): Thread {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getThread(this.handle, threadId) as Thread;
+    }
+
+    /**
+     * List threads known to the client, most recently active first
+     *
+     * @param folder - Only list threads in this folder (optional)
+     * @returns Threads
+     */
+    listThreads(folder?: ThreadFolder): Thread[] {
+        if (!this.handle) throw new Error("Not connected");
+        return native.listThreads(this.handle, folder).threads as Thread[];
+    }
+
+    /**
+     * Get a recent message known to the client, without a network request
+     *
+     * @param messageId - Message ID
+     * @returns Message
+     */
+    getMessage(messageId: string): StoredMessage {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getMessage(this.handle, messageId) as StoredMessage;
+    }
+
+    /**
+     * Get a contact known to the client, without a network request
+     *
+     * @param userId - User ID
+     * @returns Contact info
+     */
+    getContact(userId: bigint): UserInfo {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getContact(this.handle, userId);
+    }
+
+    /**
+     * Export the archived messages of a thread (requires the archivePath option)
+     *
+     * @param threadId - Thread ID
+     * @param options - Optional: format (jsonl or html) and a file to write to
+     * @returns Export result, with the content as data if no output path was given
+     */
+    async exportThread(threadId: bigint, options?: ExportThreadOptions): Promise<ExportThreadResult> {
+        if (!this.handle) throw new Error("Not connected");
+        const result = await native.exportThread(this.handle, { threadId, ...options });
+        return result as ExportThreadResult;
+    }
+
     // ========== E2EE Methods ==========
 
     /**
@@ -562,15 +1152,70 @@ export class Client<
      *
      * @param chatJid - Chat JID
      * @param text - Message text
-     * @param options - Optional: replyToId and replyToSenderJid for replies
+     * @param options - Optional: replyToId and replyToSenderJid for replies, mentions, linkPreview, idempotencyKey
+     *
+     * @example
+     * ```typescript
+     * // Mention someone and notify the whole group
+     * await client.sendE2EEMessage(chatJid, 'Hi @[Alice](100001), @everyone standup!', {
+     *     mentionMarkup: true,
+     *     mentionEveryone: true,
+     * })
+     * ```
      */
     async sendE2EEMessage(
         chatJid: string,
         text: string,
-        options?: { replyToId?: string; replyToSenderJid?: string },
+        options?: {
+            replyToId?: string;
+            replyToSenderJid?: string;
+            /** User IDs to mention */
+            mentions?: Array<{ userId: bigint; offset: number; length: number }>;
+            /** Replace `@[Name](id)` markup in the text with mentions */
+            mentionMarkup?: boolean;
+            /** Mentions located in the text by name */
+            namedMentions?: NamedMention[];
+            /** Notify all members of the group, the text must contain @everyone */
+            mentionEveryone?: boolean;
+            /** Send the text with a preview of a link in it */
+            linkPreview?: LinkPreview;
+            idempotencyKey?: string;
+        },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
-        return native.sendE2EEMessage(this.handle, chatJid, text, options?.replyToId, options?.replyToSenderJid);
+        const preview = options?.linkPreview;
+        return native.sendE2EEMessage(
+            this.handle,
+            chatJid,
+            text,
+            options?.replyToId,
+            options?.replyToSenderJid,
+            options?.idempotencyKey,
+            {
+                mentionIds: options?.mentions?.map(m => m.userId),
+                mentionOffsets: options?.mentions?.map(m => m.offset),
+                mentionLengths: options?.mentions?.map(m => m.length),
+                mentionMarkup: options?.mentionMarkup,
+                mentions: options?.namedMentions,
+                mentionEveryone: options?.mentionEveryone,
+                linkPreview: preview && {
+                    ...preview,
+                    thumbnail: preview.thumbnail && Array.from(preview.thumbnail),
+                },
+            },
+        );
+    }
+
+    /**
+     * Mark E2EE messages as read
+     *
+     * @param chatJid - Chat JID
+     * @param messageIds - Message IDs to send read receipts for
+     * @param senderJid - Sender JID, required in groups (optional)
+     */
+    async markE2EERead(chatJid: string, messageIds: string[], senderJid?: string): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.markE2EERead(this.handle, chatJid, messageIds, senderJid);
     }
 
     /**
@@ -579,11 +1224,18 @@ export class Client<
      * @param chatJid - Chat JID
      * @param messageId - Message ID
      * @param senderJid - Sender JID
-     * @param emoji - Reaction emoji (To remove it, simply omit this parameter)
+     * @param emoji - Reaction emoji (To remove it, pass an empty string or omit this parameter)
+     * @param options - Optional: idempotencyKey
      */
-    async sendE2EEReaction(chatJid: string, messageId: string, senderJid: string, emoji?: string): Promise<void> {
+    async sendE2EEReaction(
+        chatJid: string,
+        messageId: string,
+        senderJid: string,
+        emoji?: string,
+        options?: { idempotencyKey?: string },
+    ): Promise<void> {
         if (!this.handle) throw new Error("Not connected");
-        await native.sendE2EEReaction(this.handle, chatJid, messageId, senderJid, emoji || "");
+        await native.sendE2EEReaction(this.handle, chatJid, messageId, senderJid, emoji || "", options?.idempotencyKey);
     }
 
     /**
@@ -603,10 +1255,16 @@ export class Client<
      * @param chatJid - Chat JID
      * @param messageId - Message ID to edit
      * @param newText - New message text
+     * @param options - Optional: idempotencyKey
      */
-    async editE2EEMessage(chatJid: string, messageId: string, newText: string): Promise<void> {
+    async editE2EEMessage(
+        chatJid: string,
+        messageId: string,
+        newText: string,
+        options?: { idempotencyKey?: string },
+    ): Promise<void> {
         if (!this.handle) throw new Error("Not connected");
-        await native.editE2EEMessage(this.handle, chatJid, messageId, newText);
+        await native.editE2EEMessage(this.handle, chatJid, messageId, newText, options?.idempotencyKey);
     }
 
     /**
@@ -614,10 +1272,35 @@ export class Client<
      *
      * @param chatJid - Chat JID
      * @param messageId - Message ID to unsend
+     * @param options - Optional: idempotencyKey
+     */
+    async unsendE2EEMessage(chatJid: string, messageId: string, options?: { idempotencyKey?: string }): Promise<void> {
+        if (!this.handle) throw new Error("Not connected");
+        await native.unsendE2EEMessage(this.handle, chatJid, messageId, options?.idempotencyKey);
+    }
+
+    /**
+     * Forward a message to an E2EE chat
+     *
+     * The message is re-sent from the copy known to the client, so it must have been received or fetched recently.
+     * Messages with several attachments are sent as several messages.
+     *
+     * @param toChatJid - Chat JID to forward to
+     * @param messageId - Regular or E2EE message ID to forward
+     * @param options - Optional: idempotencyKey, reuse it to retry a forward that was only partially sent
+     * @returns Send result of the first forwarded message
      */
-    async unsendE2EEMessage(chatJid: string, messageId: string): Promise<void> {
+    async forwardE2EEMessage(
+        toChatJid: string,
+        messageId: string,
+        options?: { idempotencyKey?: string },
+    ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
-        await native.unsendE2EEMessage(this.handle, chatJid, messageId);
+        return native.forwardE2EEMessage(this.handle, {
+            toChatJid,
+            messageId,
+            idempotencyKey: options?.idempotencyKey,
+        });
     }
 
     // ========== E2EE Media Methods ==========
@@ -628,13 +1311,20 @@ export class Client<
      * @param chatJid - Chat JID
      * @param data - Image data as Buffer
      * @param mimeType - MIME type (e.g., image/jpeg, image/png)
-     * @param options - Optional caption, dimensions, and reply options
+     * @param options - Optional caption, dimensions, reply options and idempotencyKey
      */
     async sendE2EEImage(
         chatJid: string,
         data: Buffer,
         mimeType: string = "image/jpeg",
-        options?: { caption?: string; width?: number; height?: number; replyToId?: string; replyToSenderJid?: string },
+        options?: {
+            caption?: string;
+            width?: number;
+            height?: number;
+            replyToId?: string;
+            replyToSenderJid?: string;
+            idempotencyKey?: string;
+        },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         return native.sendE2EEImage(this.handle, {
@@ -646,6 +1336,7 @@ export class Client<
             height: options?.height,
             replyToId: options?.replyToId,
             replyToSenderJid: options?.replyToSenderJid,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -655,7 +1346,7 @@ export class Client<
      * @param chatJid - Chat JID
      * @param data - Video data as Buffer
      * @param mimeType - MIME type (default: video/mp4)
-     * @param options - Optional caption, dimensions, duration, and reply options
+     * @param options - Optional caption, dimensions, duration, reply options and idempotencyKey
      */
     async sendE2EEVideo(
         chatJid: string,
@@ -668,6 +1359,7 @@ export class Client<
             duration?: number;
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
@@ -681,6 +1373,7 @@ export class Client<
             duration: options?.duration,
             replyToId: options?.replyToId,
             replyToSenderJid: options?.replyToSenderJid,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -690,13 +1383,19 @@ export class Client<
      * @param chatJid - Chat JID
      * @param data - Audio data as Buffer
      * @param mimeType - MIME type (default: audio/ogg)
-     * @param options - Optional PTT (push-to-talk/voice message), duration, and reply options
+     * @param options - Optional PTT (push-to-talk/voice message), duration, reply options and idempotencyKey
      */
     async sendE2EEAudio(
         chatJid: string,
         data: Buffer,
         mimeType: string = "audio/ogg",
-        options?: { ptt?: boolean; duration?: number; replyToId?: string; replyToSenderJid?: string },
+        options?: {
+            ptt?: boolean;
+            duration?: number;
+            replyToId?: string;
+            replyToSenderJid?: string;
+            idempotencyKey?: string;
+        },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         return native.sendE2EEAudio(this.handle, {
@@ -707,6 +1406,7 @@ export class Client<
             duration: options?.duration,
             replyToId: options?.replyToId,
             replyToSenderJid: options?.replyToSenderJid,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -717,14 +1417,14 @@ export class Client<
      * @param data - File data as Buffer
      * @param filename - Filename
      * @param mimeType - MIME type
-     * @param options - Optional reply options
+     * @param options - Optional reply options and idempotencyKey
      */
     async sendE2EEDocument(
         chatJid: string,
         data: Buffer,
         filename: string,
         mimeType: string,
-        options?: { replyToId?: string; replyToSenderJid?: string },
+        options?: { replyToId?: string; replyToSenderJid?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         return native.sendE2EEDocument(this.handle, {
@@ -734,6 +1434,7 @@ export class Client<
             mimeType,
             replyToId: options?.replyToId,
             replyToSenderJid: options?.replyToSenderJid,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -743,13 +1444,13 @@ export class Client<
      * @param chatJid - Chat JID
      * @param data - Sticker data as Buffer (WebP format)
      * @param mimeType - MIME type (default: image/webp)
-     * @param options - Optional reply options
+     * @param options - Optional reply options and idempotencyKey
      */
     async sendE2EESticker(
         chatJid: string,
         data: Buffer,
         mimeType: string = "image/webp",
-        options?: { replyToId?: string; replyToSenderJid?: string },
+        options?: { replyToId?: string; replyToSenderJid?: string; idempotencyKey?: string },
     ): Promise<SendMessageResult> {
         if (!this.handle) throw new Error("Not connected");
         return native.sendE2EESticker(this.handle, {
@@ -758,6 +1459,7 @@ export class Client<
             mimeType,
             replyToId: options?.replyToId,
             replyToSenderJid: options?.replyToSenderJid,
+            idempotencyKey: options?.idempotencyKey,
         });
     }
 
@@ -774,6 +1476,39 @@ export class Client<
         return result.deviceData;
     }
 
+    /**
+     * Approve a changed E2EE identity key (only needed with the "manual" identity trust policy)
+     *
+     * @param address - Signal address from the identityChanged event ("user:device")
+     */
+    trustIdentity(address: string): void {
+        if (!this.handle) throw new Error("Not connected");
+        native.trustIdentity(this.handle, address);
+    }
+
+    /**
+     * Get changed E2EE identity keys waiting for approval
+     *
+     * @returns Pending identities
+     */
+    getPendingIdentities(): PendingIdentity[] {
+        if (!this.handle) throw new Error("Not connected");
+        return native.getPendingIdentities(this.handle).identities;
+    }
+
+    /**
+     * Drop all E2EE state for a contact so the next message starts a fresh session
+     *
+     * Use this when messages with a contact keep failing to decrypt.
+     *
+     * @param jid - User JID or 1:1 chat JID
+     * @returns What was removed
+     */
+    async resetE2EESession(jid: string): Promise<SessionResetResult> {
+        if (!this.handle) throw new Error("Not connected");
+        return native.resetE2EESession(this.handle, jid);
+    }
+
     /**
      * Get the current cookies from the internal client state
      *
@@ -932,6 +1667,24 @@ export class Client<
             case "deviceDataChanged":
                 this.emit("deviceDataChanged", event.data);
                 break;
+            case "identityChanged":
+                this.emit("identityChanged", event.data);
+                break;
+            case "messageStatus":
+                this.emit("messageStatus", event.data);
+                break;
+            case "outboxUpdate":
+                this.emit("outboxUpdate", event.data);
+                break;
+            case "rateLimited":
+                this.emit("rateLimited", event.data);
+                break;
+            case "scheduledMessageSent":
+                this.emit("scheduledMessageSent", event.data);
+                break;
+            case "scheduledMessageFailed":
+                this.emit("scheduledMessageFailed", event.data);
+                break;
             case "raw":
                 this.emit("raw", event.data);
                 break;
@@ -946,6 +1699,9 @@ export class Client<
             case "e2eeMessage":
             case "e2eeReaction":
             case "e2eeReceipt":
+            case "e2eeDecryptFailed":
+            case "pollUpdate":
+            case "threadUpdate":
                 if (this._fullyReadyEmitted) {
                     this.emitEvent(event);
                 } else {
@@ -984,6 +1740,15 @@ export class Client<
             case "e2eeReceipt":
                 this.emit("e2eeReceipt", event.data);
                 break;
+            case "e2eeDecryptFailed":
+                this.emit("e2eeDecryptFailed", event.data);
+                break;
+            case "pollUpdate":
+                this.emit("pollUpdate", event.data);
+                break;
+            case "threadUpdate":
+                this.emit("threadUpdate", event.data);
+                break;
         }
     }
 
@@ -84,6 +84,7 @@ const fns = {
     MxEditE2EEMessage: mk("str", "MxEditE2EEMessage", ["str"]),
     MxUnsendE2EEMessage: mk("str", "MxUnsendE2EEMessage", ["str"]),
     MxGetDeviceData: mk("str", "MxGetDeviceData", ["str"]),
+    MxMarkE2EERead: mk("str", "MxMarkE2EERead", ["str"]),
     // E2EE Media functions
     MxSendE2EEImage: mk("str", "MxSendE2EEImage", ["str"]),
     MxSendE2EEVideo: mk("str", "MxSendE2EEVideo", ["str"]),
@@ -94,6 +95,57 @@ const fns = {
     // Cookie and push notification functions
     MxGetCookies: mk("str", "MxGetCookies", ["str"]),
     MxRegisterPushNotifications: mk("str", "MxRegisterPushNotifications", ["str"]),
+    // E2EE identity functions
+    MxTrustIdentity: mk("str", "MxTrustIdentity", ["str"]),
+    MxGetPendingIdentities: mk("str", "MxGetPendingIdentities", ["str"]),
+    MxResetE2EESession: mk("str", "MxResetE2EESession", ["str"]),
+    // History functions
+    MxFetchMessages: mk("str", "MxFetchMessages", ["str"]),
+    MxFetchThreads: mk("str", "MxFetchThreads", ["str"]),
+    // State mirror functions
+    MxGetThread: mk("str", "MxGetThread", ["str"]),
+    MxListThreads: mk("str", "MxListThreads", ["str"]),
+    MxGetMessage: mk("str", "MxGetMessage", ["str"]),
+    MxGetContact: mk("str", "MxGetContact", ["str"]),
+    // Archive functions
+    MxExportThread: mk("str", "MxExportThread", ["str"]),
+    // Forward functions
+    MxForwardMessage: mk("str", "MxForwardMessage", ["str"]),
+    MxForwardE2EEMessage: mk("str", "MxForwardE2EEMessage", ["str"]),
+    // Poll functions
+    MxCreatePoll: mk("str", "MxCreatePoll", ["str"]),
+    MxUpdatePoll: mk("str", "MxUpdatePoll", ["str"]),
+    // Group functions
+    MxCreateGroup: mk("str", "MxCreateGroup", ["str"]),
+    MxAddParticipants: mk("str", "MxAddParticipants", ["str"]),
+    MxRemoveParticipant: mk("str", "MxRemoveParticipant", ["str"]),
+    MxLeaveGroup: mk("str", "MxLeaveGroup", ["str"]),
+    MxSetAdmin: mk("str", "MxSetAdmin", ["str"]),
+    MxSetApprovalMode: mk("str", "MxSetApprovalMode", ["str"]),
+    // Customization functions
+    MxSetNickname: mk("str", "MxSetNickname", ["str"]),
+    MxSetThreadTheme: mk("str", "MxSetThreadTheme", ["str"]),
+    MxSetThreadEmoji: mk("str", "MxSetThreadEmoji", ["str"]),
+    // Send status functions
+    MxGetMessageStatus: mk("str", "MxGetMessageStatus", ["str"]),
+    // Outbox functions
+    MxOutboxEnqueue: mk("str", "MxOutboxEnqueue", ["str"]),
+    MxGetOutboxItem: mk("str", "MxGetOutboxItem", ["str"]),
+    MxListOutbox: mk("str", "MxListOutbox", ["str"]),
+    MxCancelOutboxItem: mk("str", "MxCancelOutboxItem", ["str"]),
+    // Schedule functions
+    MxScheduleMessage: mk("str", "MxScheduleMessage", ["str"]),
+    MxRescheduleMessage: mk("str", "MxRescheduleMessage", ["str"]),
+    MxListScheduledMessages: mk("str", "MxListScheduledMessages", ["str"]),
+    MxCancelScheduledMessage: mk("str", "MxCancelScheduledMessage", ["str"]),
+    // Mention functions
+    MxBuildMentions: mk("str", "MxBuildMentions", ["str"]),
+    // Routing functions
+    MxResolveThread: mk("str", "MxResolveThread", ["str"]),
+    MxRegisterE2EEChat: mk("str", "MxRegisterE2EEChat", ["str"]),
+    MxReact: mk("str", "MxReact", ["str"]),
+    MxSetTyping: mk("str", "MxSetTyping", ["str"]),
+    MxMarkThreadRead: mk("str", "MxMarkThreadRead", ["str"]),
 } as const;
 
 interface JsonResp<T = unknown> {
@@ -135,6 +187,14 @@ export const native = {
         deviceData?: string;
         e2eeMemoryOnly?: boolean;
         logLevel?: string;
+        identityTrustPolicy?: string;
+        idempotencyWindowSeconds?: number;
+        stateMessagesPerThread?: number;
+        archivePath?: string;
+        outboxPath?: string;
+        rateLimit?: object;
+        scheduledMessagesPath?: string;
+        autoMarkRead?: boolean;
     }) => call<{ handle: number }>("MxNewClient", cfg),
 
     connect: (handle: number) =>
@@ -152,27 +212,32 @@ export const native = {
     sendMessage: (
         handle: number,
         options: {
-            threadId: bigint;
+            threadId?: bigint;
+            thread?: { threadId?: bigint; chatJid?: string };
             text: string;
             replyToId?: string;
             mentionIds?: bigint[];
             mentionOffsets?: number[];
             mentionLengths?: number[];
+            mentionMarkup?: boolean;
+            mentions?: Array<{ userId: bigint; name: string }>;
             attachmentFbIds?: bigint[];
             stickerId?: bigint;
             url?: string;
             isE2EE?: boolean;
             e2eeChatJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendMessage", { handle, options }),
 
-    sendReaction: (handle: number, threadId: bigint, messageId: string, emoji: string) =>
-        callAsync<unknown>("MxSendReaction", { handle, threadId, messageId, emoji }),
+    sendReaction: (handle: number, threadId: bigint, messageId: string, emoji: string, idempotencyKey?: string) =>
+        callAsync<unknown>("MxSendReaction", { handle, threadId, messageId, emoji, idempotencyKey }),
 
-    editMessage: (handle: number, messageId: string, newText: string) =>
-        callAsync<unknown>("MxEditMessage", { handle, messageId, newText }),
+    editMessage: (handle: number, messageId: string, newText: string, idempotencyKey?: string) =>
+        callAsync<unknown>("MxEditMessage", { handle, messageId, newText, idempotencyKey }),
 
-    unsendMessage: (handle: number, messageId: string) => callAsync<unknown>("MxUnsendMessage", { handle, messageId }),
+    unsendMessage: (handle: number, messageId: string, idempotencyKey?: string) =>
+        callAsync<unknown>("MxUnsendMessage", { handle, messageId, idempotencyKey }),
 
     sendTyping: (handle: number, threadId: bigint, isTyping: boolean, isGroup: boolean, threadType: number) =>
         callAsync<unknown>("MxSendTyping", { handle, threadId, isTyping, isGroup, threadType }),
@@ -193,16 +258,32 @@ export const native = {
 
     sendImage: (
         handle: number,
-        options: { threadId: bigint; data: number[]; filename: string; caption?: string; replyToId?: string },
+        options: {
+            threadId: bigint;
+            data: number[];
+            filename: string;
+            caption?: string;
+            replyToId?: string;
+            idempotencyKey?: string;
+        },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendImage", { handle, options }),
 
     sendVideo: (
         handle: number,
-        options: { threadId: bigint; data: number[]; filename: string; caption?: string; replyToId?: string },
+        options: {
+            threadId: bigint;
+            data: number[];
+            filename: string;
+            caption?: string;
+            replyToId?: string;
+            idempotencyKey?: string;
+        },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendVideo", { handle, options }),
 
-    sendVoice: (handle: number, options: { threadId: bigint; data: number[]; filename: string; replyToId?: string }) =>
-        callAsync<{ messageId: string; timestampMs: bigint }>("MxSendVoice", { handle, options }),
+    sendVoice: (
+        handle: number,
+        options: { threadId: bigint; data: number[]; filename: string; replyToId?: string; idempotencyKey?: string },
+    ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendVoice", { handle, options }),
 
     sendFile: (
         handle: number,
@@ -213,11 +294,14 @@ export const native = {
             mimeType: string;
             caption?: string;
             replyToId?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendFile", { handle, options }),
 
-    sendSticker: (handle: number, options: { threadId: bigint; stickerId: bigint; replyToId?: string }) =>
-        callAsync<{ messageId: string; timestampMs: bigint }>("MxSendSticker", { handle, options }),
+    sendSticker: (
+        handle: number,
+        options: { threadId: bigint; stickerId: bigint; replyToId?: string; idempotencyKey?: string },
+    ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendSticker", { handle, options }),
 
     createThread: (handle: number, options: { userId: bigint }) =>
         callAsync<{ threadId: bigint }>("MxCreateThread", { handle, options }),
@@ -256,29 +340,63 @@ export const native = {
     pollEvents: (handle: number, timeoutMs: number) => callAsync<unknown>("MxPollEvents", { handle, timeoutMs }),
 
     // E2EE functions
-    sendE2EEMessage: (handle: number, chatJid: string, text: string, replyToId?: string, replyToSenderJid?: string) =>
+    sendE2EEMessage: (
+        handle: number,
+        chatJid: string,
+        text: string,
+        replyToId?: string,
+        replyToSenderJid?: string,
+        idempotencyKey?: string,
+        options?: {
+            mentionIds?: bigint[];
+            mentionOffsets?: number[];
+            mentionLengths?: number[];
+            mentionMarkup?: boolean;
+            mentions?: Array<{ userId: bigint; name: string }>;
+            mentionEveryone?: boolean;
+            linkPreview?: {
+                url?: string;
+                title?: string;
+                description?: string;
+                thumbnail?: number[];
+                thumbnailWidth?: number;
+                thumbnailHeight?: number;
+            };
+        },
+    ) =>
         callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EEMessage", {
             handle,
             chatJid,
             text,
             replyToId,
             replyToSenderJid,
+            idempotencyKey,
+            ...options,
         }),
 
-    sendE2EEReaction: (handle: number, chatJid: string, messageId: string, senderJid: string, emoji: string) =>
-        callAsync<unknown>("MxSendE2EEReaction", { handle, chatJid, messageId, senderJid, emoji }),
+    sendE2EEReaction: (
+        handle: number,
+        chatJid: string,
+        messageId: string,
+        senderJid: string,
+        emoji: string,
+        idempotencyKey?: string,
+    ) => callAsync<unknown>("MxSendE2EEReaction", { handle, chatJid, messageId, senderJid, emoji, idempotencyKey }),
 
     sendE2EETyping: (handle: number, chatJid: string, isTyping: boolean) =>
         callAsync<unknown>("MxSendE2EETyping", { handle, chatJid, isTyping }),
 
-    editE2EEMessage: (handle: number, chatJid: string, messageId: string, newText: string) =>
-        callAsync<unknown>("MxEditE2EEMessage", { handle, chatJid, messageId, newText }),
+    editE2EEMessage: (handle: number, chatJid: string, messageId: string, newText: string, idempotencyKey?: string) =>
+        callAsync<unknown>("MxEditE2EEMessage", { handle, chatJid, messageId, newText, idempotencyKey }),
 
-    unsendE2EEMessage: (handle: number, chatJid: string, messageId: string) =>
-        callAsync<unknown>("MxUnsendE2EEMessage", { handle, chatJid, messageId }),
+    unsendE2EEMessage: (handle: number, chatJid: string, messageId: string, idempotencyKey?: string) =>
+        callAsync<unknown>("MxUnsendE2EEMessage", { handle, chatJid, messageId, idempotencyKey }),
 
     getDeviceData: (handle: number) => call<{ deviceData: string }>("MxGetDeviceData", { handle }),
 
+    markE2EERead: (handle: number, chatJid: string, messageIds: string[], senderJid?: string) =>
+        callAsync<unknown>("MxMarkE2EERead", { handle, chatJid, messageIds, senderJid }),
+
     // E2EE Media functions
     sendE2EEImage: (
         handle: number,
@@ -291,6 +409,7 @@ export const native = {
             height?: number;
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EEImage", { handle, options }),
 
@@ -306,6 +425,7 @@ export const native = {
             duration?: number;
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EEVideo", { handle, options }),
 
@@ -319,6 +439,7 @@ export const native = {
             ptt?: boolean; // Push-to-talk (voice message)
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EEAudio", { handle, options }),
 
@@ -331,6 +452,7 @@ export const native = {
             mimeType: string;
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EEDocument", { handle, options }),
 
@@ -342,6 +464,7 @@ export const native = {
             mimeType: string;
             replyToId?: string;
             replyToSenderJid?: string;
+            idempotencyKey?: string;
         },
     ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxSendE2EESticker", { handle, options }),
 
@@ -369,5 +492,186 @@ export const native = {
         },
     ) => callAsync<unknown>("MxRegisterPushNotifications", { handle, options }),
 
+    // E2EE identity functions
+    trustIdentity: (handle: number, address: string) => call<unknown>("MxTrustIdentity", { handle, address }),
+
+    getPendingIdentities: (handle: number) =>
+        call<{
+            identities: { address: string; userId?: bigint; oldFingerprint: string; newFingerprint: string }[];
+        }>("MxGetPendingIdentities", { handle }),
+
+    resetE2EESession: (handle: number, jid: string) =>
+        callAsync<{
+            userJid: string;
+            sessions: string[];
+            identities: string[];
+            senderKeys: number;
+        }>("MxResetE2EESession", { handle, userJid: jid }),
+
+    // History functions
+    fetchMessages: (
+        handle: number,
+        options: { threadId: bigint; beforeTimestampMs?: bigint; cursor?: string; limit?: number },
+    ) =>
+        callAsync<{ messages: unknown[]; cursor?: string; hasMore: boolean }>("MxFetchMessages", { handle, options }),
+
+    fetchThreads: (handle: number, options: { folder?: string; cursor?: string; limit?: number }) =>
+        callAsync<{ threads: unknown[]; cursor?: string; hasMore: boolean }>("MxFetchThreads", { handle, options }),
+
+    // State mirror functions
+    getThread: (handle: number, threadId: bigint) => call<unknown>("MxGetThread", { handle, threadId }),
+
+    listThreads: (handle: number, folder?: string) => call<{ threads: unknown[] }>("MxListThreads", { handle, folder }),
+
+    getMessage: (handle: number, messageId: string) => call<unknown>("MxGetMessage", { handle, messageId }),
+
+    getContact: (handle: number, userId: bigint) =>
+        call<{
+            id: bigint;
+            name: string;
+            firstName?: string;
+            username?: string;
+            profilePictureUrl?: string;
+            isMessengerUser?: boolean;
+            isVerified?: boolean;
+            gender?: number;
+            canViewerMessage?: boolean;
+        }>("MxGetContact", { handle, userId }),
+
+    // Archive functions
+    exportThread: (handle: number, options: { threadId: bigint; format?: string; outputPath?: string }) =>
+        callAsync<{ format: string; records: number; path?: string; data?: string }>("MxExportThread", {
+            handle,
+            options,
+        }),
+
+    // Forward functions
+    forwardMessage: (
+        handle: number,
+        options: { toThreadId: bigint; forwardedMsgId: string; idempotencyKey?: string },
+    ) => callAsync<{ messageId: string; timestampMs: bigint }>("MxForwardMessage", { handle, options }),
+
+    forwardE2EEMessage: (handle: number, options: { toChatJid: string; messageId: string; idempotencyKey?: string }) =>
+        callAsync<{ messageId: string; timestampMs: bigint }>("MxForwardE2EEMessage", { handle, options }),
+
+    // Poll functions
+    createPoll: (handle: number, options: { threadId: bigint; question: string; options: string[] }) =>
+        callAsync<unknown>("MxCreatePoll", { handle, options }),
+
+    updatePoll: (
+        handle: number,
+        options: { threadId: bigint; pollId: bigint; selectedOptions: bigint[]; addedOptions?: string[] },
+    ) => callAsync<unknown>("MxUpdatePoll", { handle, options }),
+
+    // Group functions
+    createGroup: (handle: number, options: { userIds: bigint[]; name?: string }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxCreateGroup", { handle, options }),
+
+    addParticipants: (handle: number, options: { threadId: bigint; userIds: bigint[] }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxAddParticipants", { handle, options }),
+
+    removeParticipant: (handle: number, options: { threadId: bigint; userId: bigint }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxRemoveParticipant", { handle, options }),
+
+    leaveGroup: (handle: number, options: { threadId: bigint }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxLeaveGroup", { handle, options }),
+
+    setAdmin: (handle: number, options: { threadId: bigint; userId: bigint; isAdmin: boolean }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxSetAdmin", { handle, options }),
+
+    setApprovalMode: (handle: number, options: { threadId: bigint; enabled: boolean }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxSetApprovalMode", { handle, options }),
+
+    // Customization functions
+    setNickname: (handle: number, options: { threadId: bigint; userId: bigint; nickname: string }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxSetNickname", { handle, options }),
+
+    setThreadTheme: (handle: number, options: { threadId: bigint; theme: string }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxSetThreadTheme", { handle, options }),
+
+    setThreadEmoji: (handle: number, options: { threadId: bigint; emoji: string }) =>
+        callAsync<{ threadId: bigint; thread?: unknown }>("MxSetThreadEmoji", { handle, options }),
+
+    // Send status functions
+    getMessageStatus: (handle: number, trackingId: string) =>
+        call<unknown>("MxGetMessageStatus", { handle, trackingId }),
+
+    // Outbox functions
+    outboxEnqueue: (handle: number, options: { kind: string; payload: Record<string, unknown> }) =>
+        call<unknown>("MxOutboxEnqueue", { handle, options }),
+
+    getOutboxItem: (handle: number, id: string) => call<unknown>("MxGetOutboxItem", { handle, id }),
+
+    listOutbox: (handle: number, status?: string) => call<unknown[]>("MxListOutbox", { handle, status }),
+
+    cancelOutboxItem: (handle: number, id: string) => call<unknown>("MxCancelOutboxItem", { handle, id }),
+
+    // Schedule functions
+    scheduleMessage: (
+        handle: number,
+        options: {
+            threadId?: bigint;
+            chatJid?: string;
+            content: {
+                text: string;
+                replyToId?: string;
+                mentionIds?: bigint[];
+                mentionOffsets?: number[];
+                mentionLengths?: number[];
+                mentionMarkup?: boolean;
+                mentions?: Array<{ userId: bigint; name: string }>;
+                attachmentFbIds?: bigint[];
+                idempotencyKey?: string;
+            };
+            sendAtMs: number;
+        },
+    ) => call<unknown>("MxScheduleMessage", { handle, options }),
+
+    rescheduleMessage: (handle: number, id: string, sendAtMs: number) =>
+        call<unknown>("MxRescheduleMessage", { handle, options: { id, sendAtMs } }),
+
+    listScheduledMessages: (handle: number, status?: string) =>
+        call<unknown[]>("MxListScheduledMessages", { handle, status }),
+
+    cancelScheduledMessage: (handle: number, id: string) => call<unknown>("MxCancelScheduledMessage", { handle, id }),
+
+    // Mention functions
+    buildMentions: (options: { text: string; markup?: boolean; mentions?: Array<{ userId: bigint; name: string }> }) =>
+        call<{ text: string; mentionIds: bigint[]; mentionOffsets: number[]; mentionLengths: number[] }>(
+            "MxBuildMentions",
+            options,
+        ),
+
+    // Routing functions
+    resolveThread: (handle: number, options: { threadId?: bigint; chatJid?: string }) =>
+        call<unknown>("MxResolveThread", { handle, options }),
+
+    registerE2EEChat: (handle: number, options: { threadId?: bigint; chatJid: string }) =>
+        call<unknown>("MxRegisterE2EEChat", { handle, options }),
+
+    react: (
+        handle: number,
+        options: {
+            thread: { threadId?: bigint; chatJid?: string };
+            messageId: string;
+            senderJid?: string;
+            emoji: string;
+            idempotencyKey?: string;
+        },
+    ) => callAsync<unknown>("MxReact", { handle, options }),
+
+    setTyping: (handle: number, options: { thread: { threadId?: bigint; chatJid?: string }; isTyping: boolean }) =>
+        callAsync<unknown>("MxSetTyping", { handle, options }),
+
+    markThreadRead: (
+        handle: number,
+        options: {
+            thread: { threadId?: bigint; chatJid?: string };
+            watermarkTs?: number;
+            messageIds?: string[];
+            senderJid?: string;
+        },
+    ) => callAsync<unknown>("MxMarkThreadRead", { handle, options }),
+
     unload: () => lib.unload(),
 };
@@ -37,6 +37,15 @@ export type EventType =
     | "e2eeReaction"
     | "e2eeReceipt"
     | "deviceDataChanged"
+    | "e2eeDecryptFailed"
+    | "identityChanged"
+    | "pollUpdate"
+    | "threadUpdate"
+    | "messageStatus"
+    | "outboxUpdate"
+    | "rateLimited"
+    | "scheduledMessageSent"
+    | "scheduledMessageFailed"
     | "raw";
 
 /**
@@ -99,13 +108,26 @@ export interface MessageEvent extends BaseEvent {
  */
 export interface MessageEditEvent extends BaseEvent {
     type: "messageEdit";
-    data: {
-        messageId: string;
-        threadId: bigint;
-        newText: string;
-        editCount?: bigint;
-        timestampMs?: bigint;
-    };
+    data: MessageEditData;
+}
+
+/**
+ * Message edit info. Thread, sender and previous content are filled in when the original message is known
+ */
+export interface MessageEditData {
+    messageId: string;
+    /** 0 if the original message isn't known */
+    threadId: bigint;
+    /** Chat JID (E2EE only) */
+    chatJid?: string;
+    senderId?: bigint;
+    newText: string;
+    /** Text before the edit, if the original message is known */
+    previousText?: string;
+    attachments?: Attachment[];
+    editCount: bigint;
+    isE2EE?: boolean;
+    timestampMs: bigint;
 }
 
 /**
@@ -113,10 +135,23 @@ export interface MessageEditEvent extends BaseEvent {
  */
 export interface MessageUnsendEvent extends BaseEvent {
     type: "messageUnsend";
-    data: {
-        messageId: string;
-        threadId: bigint;
-    };
+    data: MessageUnsendData;
+}
+
+/**
+ * Message unsend info. Thread, sender and previous content are filled in when the original message is known
+ */
+export interface MessageUnsendData {
+    messageId: string;
+    threadId: bigint;
+    /** Chat JID (E2EE only) */
+    chatJid?: string;
+    senderId?: bigint;
+    /** Text of the unsent message, if it is known */
+    previousText?: string;
+    attachments?: Attachment[];
+    isE2EE?: boolean;
+    timestampMs: bigint;
 }
 
 /**
@@ -209,6 +244,196 @@ export interface DeviceDataChangedEvent extends BaseEvent {
     };
 }
 
+/**
+ * E2EE decrypt failed event - emitted when an E2EE message couldn't be decrypted
+ */
+export interface E2EEDecryptFailedEvent extends BaseEvent {
+    type: "e2eeDecryptFailed";
+    data: E2EEDecryptFailedData;
+}
+
+/**
+ * E2EE decrypt failure info
+ */
+export interface E2EEDecryptFailedData {
+    messageId: string;
+    chatJid: string;
+    senderJid: string;
+    senderId: bigint;
+    /** "decryptFailed", "unavailable" or a specific unavailable type such as "view_once" */
+    reason: string;
+    /** Whether the message was hidden and shouldn't be shown as failed */
+    hidden?: boolean;
+    timestampMs: bigint;
+}
+
+/**
+ * Identity changed event - emitted when a contact's E2EE identity key changes
+ */
+export interface IdentityChangedEvent extends BaseEvent {
+    type: "identityChanged";
+    data: IdentityChangedData;
+}
+
+/**
+ * Identity key change info
+ */
+export interface IdentityChangedData {
+    /** Signal address ("user:device") */
+    address: string;
+    userId?: bigint;
+    oldFingerprint: string;
+    newFingerprint: string;
+    policy: IdentityTrustPolicy;
+    /** Whether the new key was accepted without approval */
+    trusted: boolean;
+    /** Whether trustIdentity must be called before the new key is accepted */
+    pendingApproval?: boolean;
+    timestampMs: bigint;
+}
+
+/**
+ * Poll update event - emitted when a poll is created or its votes or options change
+ */
+export interface PollUpdateEvent extends BaseEvent {
+    type: "pollUpdate";
+    data: PollUpdateData;
+}
+
+/**
+ * Poll update info
+ */
+export interface PollUpdateData {
+    pollId: bigint;
+    threadId: bigint;
+    /** Message carrying the updated poll card */
+    messageId: string;
+    /** Sender of that message, usually the voter for vote notices */
+    senderId: bigint;
+    poll: Poll;
+    /** Poll before the update, unset if the poll wasn't seen before */
+    previous?: Poll;
+    timestampMs: bigint;
+}
+
+/**
+ * Thread update event - emitted when a thread's settings or members change.
+ * Differences found while syncing or backfilling aren't reported
+ */
+export interface ThreadUpdateEvent extends BaseEvent {
+    type: "threadUpdate";
+    data: ThreadUpdateData;
+}
+
+/**
+ * Kind of change in a thread update
+ */
+export type ThreadUpdateKind =
+    | "rename"
+    | "photoChanged"
+    | "participantsAdded"
+    | "participantsRemoved"
+    | "adminChanged"
+    | "nicknameChanged"
+    | "themeChanged"
+    | "emojiChanged";
+
+/**
+ * Thread update info
+ *
+ * `before` and `after` hold the name, picture URL, nickname or emoji (strings), the theme FBID (bigint)
+ * or the admin flag (boolean). They are null for participant additions and removals, which list the users in `userIds`.
+ */
+export interface ThreadUpdateData {
+    threadId: bigint;
+    kind: ThreadUpdateKind;
+    /** User who made the change, if known */
+    actorId?: bigint;
+    /** Affected participants */
+    userIds?: bigint[];
+    before: string | bigint | boolean | null;
+    after: string | bigint | boolean | null;
+    timestampMs: bigint;
+}
+
+/**
+ * Message status event - emitted when the delivery state of a sent message changes
+ */
+export interface MessageStatusEvent extends BaseEvent {
+    type: "messageStatus";
+    data: MessageStatus;
+}
+
+/**
+ * Delivery state of a sent message. Only moves forward, unless the send fails
+ */
+export type SendStatus = "pending" | "sent" | "delivered" | "read" | "failed";
+
+/**
+ * Tracked state of a sent message
+ *
+ * In groups, delivered and read mean at least one other participant received or read it.
+ */
+export interface MessageStatus {
+    /** The tracking ID from the send result */
+    trackingId: string;
+    /** Unset until the server assigns one */
+    messageId?: string;
+    threadId: bigint;
+    /** Chat JID (E2EE only) */
+    chatJid?: string;
+    isE2EE?: boolean;
+    status: SendStatus;
+    /** Failure reason */
+    reason?: string;
+    /** Local time of the send */
+    sentAtMs: bigint;
+    updatedAtMs: bigint;
+    /** Server time of the message once known, delivery and read receipts are compared with it */
+    serverTimestampMs?: bigint;
+}
+
+/**
+ * Outbox update event - emitted when an outbox item is queued, attempted, sent, failed or cancelled
+ */
+export interface OutboxUpdateEvent extends BaseEvent {
+    type: "outboxUpdate";
+    data: OutboxItem;
+}
+
+/**
+ * Rate limited event - emitted when a send is delayed or rejected by the rate limiter
+ */
+export interface RateLimitedEvent extends BaseEvent {
+    type: "rateLimited";
+    data: RateLimitedData;
+}
+
+export interface RateLimitedData {
+    category: RateCategory;
+    threadId?: bigint;
+    /** How long the call waits, or would have to wait if rejected */
+    waitMs: number;
+    /** The call failed with a rate limited error */
+    rejected: boolean;
+}
+
+/**
+ * Scheduled message sent event
+ */
+export interface ScheduledMessageSentEvent extends BaseEvent {
+    type: "scheduledMessageSent";
+    data: ScheduledMessage;
+}
+
+/**
+ * Scheduled message failed event - emitted when a scheduled message failed permanently or ran out of retries
+ */
+export interface ScheduledMessageFailedEvent extends BaseEvent {
+    type: "scheduledMessageFailed";
+    data: ScheduledMessage;
+}
+
 /**
  * Raw event source - indicates which channel the event came from
  */
@@ -256,6 +481,15 @@ export type ClientEvent =
     | E2EEReactionEvent
     | E2EEReceiptEvent
     | DeviceDataChangedEvent
+    | E2EEDecryptFailedEvent
+    | IdentityChangedEvent
+    | PollUpdateEvent
+    | ThreadUpdateEvent
+    | MessageStatusEvent
+    | OutboxUpdateEvent
+    | RateLimitedEvent
+    | ScheduledMessageSentEvent
+    | ScheduledMessageFailedEvent
     | RawEvent;
 
 /**
@@ -276,6 +510,36 @@ export interface Thread {
     name: string;
     lastActivityTimestampMs: bigint;
     snippet: string;
+    isGroup: boolean;
+    /** Folder the thread is in ("inbox", "pending", "archived", "spam", ...) */
+    folder?: string;
+    parentThreadKey?: bigint;
+    pictureUrl?: string;
+    /** Chat JID, set for threads encrypted over WhatsApp */
+    e2eeChatJid?: string;
+    /** Mute expiry in milliseconds (-1 = muted indefinitely) */
+    mutedUntilMs?: bigint;
+    lastReadWatermarkTimestampMs: bigint;
+    isUnread: boolean;
+    /** Unread messages, counted from messages known to the client */
+    unreadCount: number;
+    needsAdminApproval?: boolean;
+    customEmoji?: string;
+    themeFbid?: bigint;
+    memberCount?: bigint;
+    participants: ThreadParticipant[];
+}
+
+/**
+ * Thread member
+ */
+export interface ThreadParticipant {
+    userId: bigint;
+    nickname?: string;
+    isAdmin?: boolean;
+    isSuperAdmin?: boolean;
+    readWatermarkTimestampMs?: bigint;
+    deliveredWatermarkTimestampMs?: bigint;
 }
 
 /**
@@ -293,7 +557,17 @@ export enum ThreadType {
 /**
  * Attachment type
  */
-export type AttachmentType = "image" | "video" | "audio" | "file" | "sticker" | "gif" | "voice" | "location" | "link";
+export type AttachmentType =
+    | "image"
+    | "video"
+    | "audio"
+    | "file"
+    | "sticker"
+    | "gif"
+    | "voice"
+    | "location"
+    | "link"
+    | "poll";
 
 /**
  * Media attachment
@@ -334,6 +608,28 @@ export interface Attachment {
     mediaEncSha256?: string;
     /** Direct path for E2EE media download (E2EE only) */
     directPath?: string;
+    /** Poll (for poll attachments) */
+    poll?: Poll;
+}
+
+/**
+ * Poll
+ */
+export interface Poll {
+    id: bigint;
+    question: string;
+    /** Poll cards only include the first three options */
+    options: PollOption[];
+}
+
+/**
+ * Poll option and its votes
+ */
+export interface PollOption {
+    id: bigint;
+    text: string;
+    voteCount: bigint;
+    votePercent: bigint;
 }
 
 /**
@@ -400,6 +696,20 @@ export interface E2EEMessage extends BaseMessage {
     senderJid: string;
 }
 
+/**
+ * Recent message kept by the client's state mirror
+ */
+export interface StoredMessage extends BaseMessage {
+    /** Chat JID (E2EE only) */
+    chatJid?: string;
+    /** Sender JID (E2EE only) */
+    senderJid?: string;
+    isE2EE?: boolean;
+    isAdminMsg?: boolean;
+    editCount?: bigint;
+    isUnsent?: boolean;
+}
+
 /**
  * Read receipt event data
  */
@@ -410,6 +720,57 @@ export interface ReadReceiptData {
     timestampMs?: bigint;
 }
 
+/**
+ * Message history fetch options
+ */
+export interface FetchMessagesOptions {
+    /** Fetch messages older than this timestamp (ignored if cursor is set) */
+    beforeTimestampMs?: bigint;
+    /** Cursor from a previous page */
+    cursor?: string;
+    /** Maximum number of messages */
+    limit?: number;
+}
+
+/**
+ * Page of message history, newest messages last
+ */
+export interface MessagePage {
+    messages: Message[];
+    /** Pass to fetchMessages for older messages */
+    cursor?: string;
+    hasMore: boolean;
+}
+
+/**
+ * Thread list folder
+ * - `pending`: message requests
+ * - `spam`: spam and filtered requests
+ */
+export type ThreadFolder = "inbox" | "pending" | "archived" | "spam";
+
+/**
+ * Thread list fetch options
+ */
+export interface FetchThreadsOptions {
+    /** Folder to list. Default: "inbox" */
+    folder?: ThreadFolder;
+    /** Cursor from a previous page */
+    cursor?: string;
+    /** Maximum number of threads */
+    limit?: number;
+}
+
+/**
+ * Page of threads, most recently active first
+ */
+export interface ThreadPage {
+    threads: Thread[];
+    /** Pass to fetchThreads for older threads */
+    cursor?: string;
+    hasMore: boolean;
+}
+
 /**
  * Initial data received on connect
  */
@@ -428,6 +789,38 @@ export type Platform = "facebook" | "messenger" | "instagram";
  */
 export type LogLevel = "trace" | "debug" | "info" | "warn" | "error" | "none";
 
+/**
+ * How changed E2EE identity keys are handled
+ * - `tofu`: trust the first key seen, a changed key is accepted on the next attempt
+ * - `always`: accept changed keys immediately and only notify
+ * - `manual`: block changed keys until approved with `trustIdentity`
+ */
+export type IdentityTrustPolicy = "tofu" | "always" | "manual";
+
+/**
+ * Changed identity key waiting for approval
+ */
+export interface PendingIdentity {
+    /** Signal address ("user:device") */
+    address: string;
+    userId?: bigint;
+    oldFingerprint: string;
+    newFingerprint: string;
+}
+
+/**
+ * Result of resetting an E2EE session
+ */
+export interface SessionResetResult {
+    userJid: string;
+    /** Signal addresses whose sessions were deleted */
+    sessions: string[];
+    /** Signal addresses whose identity keys were forgotten */
+    identities: string[];
+    /** Number of group sender keys received from the contact that were dropped */
+    senderKeys: number;
+}
+
 /**
  * Cookies required for authentication
  */
@@ -457,6 +850,62 @@ export interface ClientOptions {
     enableE2EE?: boolean;
     /** Auto reconnect on disconnect */
     autoReconnect?: boolean;
+    /** How changed E2EE identity keys are handled. Default: "tofu" */
+    identityTrustPolicy?: IdentityTrustPolicy;
+    /** How many recent messages per thread the state mirror keeps. Default: 100 */
+    stateMessagesPerThread?: number;
+    /** Directory for the local message archive (one JSONL file per thread). Disabled if unset */
+    archivePath?: string;
+    /** How long results of sends with an idempotency key are remembered, in seconds. Default: 86400 (24h) */
+    idempotencyWindowSeconds?: number;
+    /** JSON file to persist queued outbox sends to, so they survive restarts. Memory only if unset */
+    outboxPath?: string;
+    /** Throttle outgoing sends. Off if unset, `{}` turns it on with the default budgets */
+    rateLimit?: RateLimitConfig;
+    /** JSON file to persist scheduled messages to, so they survive restarts. Memory only if unset */
+    scheduledMessagesPath?: string;
+    /**
+     * Send read receipts for incoming regular and E2EE messages as soon as they're received, not after your
+     * handlers ran. Default: false
+     */
+    autoMarkRead?: boolean;
+}
+
+/**
+ * Class of outgoing traffic with its own budget.
+ * "messages" covers messages, media, forwards, edits and unsends
+ */
+export type RateCategory = "messages" | "reactions" | "typing";
+
+/**
+ * Token bucket for the account and one for each thread
+ */
+export interface RateBudget {
+    /** Account-wide refill rate */
+    perSecond: number;
+    /** Account-wide bucket size */
+    burst: number;
+    /** Refill rate per thread */
+    threadPerSecond: number;
+    /** Bucket size per thread */
+    threadBurst: number;
+}
+
+/**
+ * Outgoing rate limiter config. Budgets left unset use the defaults
+ */
+export interface RateLimitConfig {
+    disabled?: boolean;
+    /** Fail with a rate limited error instead of waiting for a token */
+    failFast?: boolean;
+    /** Longest a call waits before failing anyway. Default: 30000 */
+    maxWaitMs?: number;
+    /** Default: 2/s, burst 10, 1/s and burst 5 per thread */
+    messages?: RateBudget;
+    /** Default: 3/s, burst 10, 2/s and burst 5 per thread */
+    reactions?: RateBudget;
+    /** Default: 2/s, burst 5, 0.5/s and burst 2 per thread */
+    typing?: RateBudget;
 }
 
 /**
@@ -475,6 +924,65 @@ export interface SendMessageOptions {
         offset: number;
         length: number;
     }>;
+    /** Replace `@[Name](id)` markup in the text with mentions, instead of giving offsets */
+    mentionMarkup?: boolean;
+    /** Mentions located in the text by name, instead of giving offsets */
+    namedMentions?: NamedMention[];
+    /** Retries with the same key return the first result instead of sending again */
+    idempotencyKey?: string;
+}
+
+/**
+ * Thread in either form. A chat JID always means E2EE, a thread ID is E2EE if its chat is known
+ */
+export interface ThreadRef {
+    threadId?: bigint;
+    chatJid?: string;
+}
+
+/**
+ * Where sends to a thread go
+ */
+export interface ResolvedThread {
+    threadId: bigint;
+    /** Set for E2EE threads */
+    chatJid?: string;
+    isE2EE: boolean;
+}
+
+/**
+ * Preview of a link in an E2EE text message
+ */
+export interface LinkPreview {
+    /** Default: the first link in the text */
+    url?: string;
+    title?: string;
+    description?: string;
+    /** JPEG thumbnail */
+    thumbnail?: Buffer;
+    thumbnailWidth?: number;
+    thumbnailHeight?: number;
+}
+
+/**
+ * Mention located in the text by name
+ */
+export interface NamedMention {
+    userId: bigint;
+    /** Matched as "@Name" first, then as "Name" */
+    name: string;
+}
+
+/**
+ * Text with mentions ready to send. Offsets and lengths are in UTF-16 code units, like JavaScript strings
+ */
+export interface MentionedText {
+    text: string;
+    mentions: Array<{
+        userId: bigint;
+        offset: number;
+        length: number;
+    }>;
 }
 
 /**
@@ -483,6 +991,227 @@ export interface SendMessageOptions {
 export interface SendMessageResult {
     messageId: string;
     timestampMs: bigint;
+    /** For getMessageStatus and messageStatus events */
+    trackingId?: string;
+}
+
+/**
+ * State of a scheduled message
+ */
+export type ScheduleStatus = "scheduled" | "sending" | "sent" | "failed" | "cancelled";
+
+/**
+ * Thread a message is scheduled to, a regular thread or an E2EE chat
+ */
+export type ScheduleTarget = { threadId: bigint } | { chatJid: string };
+
+/**
+ * Message waiting to be sent at a set time
+ */
+export interface ScheduledMessage {
+    id: string;
+    threadId?: bigint;
+    /** Set for E2EE chats */
+    chatJid?: string;
+    content: {
+        text: string;
+        replyToId?: string;
+        mentionIds?: bigint[];
+        mentionOffsets?: number[];
+        mentionLengths?: number[];
+        attachmentFbIds?: bigint[];
+        idempotencyKey?: string;
+    };
+    sendAtMs: bigint;
+    status: ScheduleStatus;
+    /** Transport errors and timeouts are retried with backoff, other errors mark the message failed at once */
+    attempts: number;
+    /** Error of the last attempt */
+    error?: string;
+    /** Set while waiting to retry */
+    nextAttemptAtMs?: bigint;
+    result?: SendMessageResult;
+    createdAtMs: bigint;
+    updatedAtMs: bigint;
+}
+
+/**
+ * Thread export format
+ */
+export type ExportFormat = "jsonl" | "html";
+
+/**
+ * Export thread options
+ */
+export interface ExportThreadOptions {
+    /** Default: "jsonl" */
+    format?: ExportFormat;
+    /** File to write the export to. If unset, the export is returned as data */
+    outputPath?: string;
+}
+
+/**
+ * Export thread result
+ */
+export interface ExportThreadResult {
+    format: ExportFormat;
+    /** Number of archived records exported */
+    records: number;
+    /** Path the export was written to */
+    path?: string;
+    /** Export content, if no output path was given */
+    data?: string;
+}
+
+/**
+ * Kind of send an outbox item performs
+ */
+export type OutboxKind = keyof OutboxPayloads;
+
+/**
+ * Delivery state of an outbox item
+ */
+export type OutboxStatus = "queued" | "sending" | "sent" | "failed" | "cancelled";
+
+/**
+ * Media data for outbox payloads, as a Buffer or a base64 string
+ */
+export type OutboxData = Buffer | string;
+
+/**
+ * Outbox payloads by kind. Each takes the options of the matching send
+ */
+export interface OutboxPayloads {
+    message: {
+        threadId?: bigint;
+        text: string;
+        replyToId?: string;
+        mentionIds?: bigint[];
+        mentionOffsets?: number[];
+        mentionLengths?: number[];
+        mentionMarkup?: boolean;
+        mentions?: NamedMention[];
+        attachmentFbIds?: bigint[];
+        stickerId?: bigint;
+        url?: string;
+        isE2EE?: boolean;
+        e2eeChatJid?: string;
+        e2eeReplyToId?: string;
+        e2eeReplyToSenderJid?: string;
+        /** Takes precedence over threadId and e2eeChatJid */
+        thread?: ThreadRef;
+        mentionEveryone?: boolean;
+        linkPreview?: LinkPreview;
+        idempotencyKey?: string;
+    };
+    media: { threadId: bigint; mediaFbIds: bigint[]; caption?: string; replyToId?: string; idempotencyKey?: string };
+    image: {
+        threadId: bigint;
+        data: OutboxData;
+        filename: string;
+        caption?: string;
+        replyToId?: string;
+        idempotencyKey?: string;
+    };
+    video: {
+        threadId: bigint;
+        data: OutboxData;
+        filename: string;
+        caption?: string;
+        replyToId?: string;
+        idempotencyKey?: string;
+    };
+    voice: { threadId: bigint; data: OutboxData; filename: string; replyToId?: string; idempotencyKey?: string };
+    file: {
+        threadId: bigint;
+        data: OutboxData;
+        filename: string;
+        mimeType: string;
+        caption?: string;
+        replyToId?: string;
+        idempotencyKey?: string;
+    };
+    sticker: { threadId: bigint; stickerId: bigint; replyToId?: string; idempotencyKey?: string };
+    e2eeImage: {
+        chatJid: string;
+        data: OutboxData;
+        mimeType: string;
+        caption?: string;
+        width?: number;
+        height?: number;
+        replyToId?: string;
+        replyToSenderJid?: string;
+        idempotencyKey?: string;
+    };
+    e2eeVideo: {
+        chatJid: string;
+        data: OutboxData;
+        mimeType: string;
+        caption?: string;
+        width?: number;
+        height?: number;
+        duration?: number;
+        replyToId?: string;
+        replyToSenderJid?: string;
+        idempotencyKey?: string;
+    };
+    e2eeAudio: {
+        chatJid: string;
+        data: OutboxData;
+        mimeType: string;
+        duration?: number;
+        ptt?: boolean;
+        replyToId?: string;
+        replyToSenderJid?: string;
+        idempotencyKey?: string;
+    };
+    e2eeDocument: {
+        chatJid: string;
+        data: OutboxData;
+        filename: string;
+        mimeType: string;
+        replyToId?: string;
+        replyToSenderJid?: string;
+        idempotencyKey?: string;
+    };
+    e2eeSticker: {
+        chatJid: string;
+        data: OutboxData;
+        mimeType: string;
+        width?: number;
+        height?: number;
+        replyToId?: string;
+        replyToSenderJid?: string;
+        idempotencyKey?: string;
+    };
+    /** Empty emoji removes the reaction */
+    reaction: { threadId: bigint; messageId: string; emoji: string; idempotencyKey?: string };
+    e2eeReaction: { chatJid: string; messageId: string; senderJid: string; emoji: string; idempotencyKey?: string };
+    /** threadId orders the edit with other sends to the thread, looked up if unset */
+    edit: { threadId?: bigint; messageId: string; newText: string; idempotencyKey?: string };
+    e2eeEdit: { chatJid: string; messageId: string; newText: string; idempotencyKey?: string };
+    /** threadId orders the unsend with other sends to the thread, looked up if unset */
+    unsend: { threadId?: bigint; messageId: string; idempotencyKey?: string };
+    e2eeUnsend: { chatJid: string; messageId: string; idempotencyKey?: string };
+}
+
+/**
+ * Queued send and its delivery state
+ */
+export interface OutboxItem {
+    id: string;
+    kind: OutboxKind;
+    /** Queue of the item ("thread:<id>", "chat:<jid>" or "message:<id>"), items with the same key are sent in order */
+    threadKey: string;
+    /** "failed" after a permanent error, or once transient errors were retried too often */
+    status: OutboxStatus;
+    attempts: number;
+    lastError?: string;
+    nextAttemptAtMs?: bigint;
+    /** Set when a message-producing item is sent */
+    result?: SendMessageResult;
+    createdAtMs: bigint;
+    updatedAtMs: bigint;
 }
 
 /**
@@ -509,6 +1238,36 @@ export interface CreateThreadResult {
     threadId: bigint;
 }
 
+/**
+ * Thread color theme names
+ */
+export type ThreadThemeName =
+    | "default"
+    | "hotPink"
+    | "aquaBlue"
+    | "purple"
+    | "coral"
+    | "orange"
+    | "green"
+    | "lavender"
+    | "red"
+    | "yellow"
+    | "tealBlue"
+    | "aqua"
+    | "mango"
+    | "berry"
+    | "citrus"
+    | "candy";
+
+/**
+ * State of a group after a management operation
+ */
+export interface GroupUpdateResult {
+    threadId: bigint;
+    /** Unset if the thread isn't known to the client yet */
+    thread?: Thread;
+}
+
 /**
  * User information
  */